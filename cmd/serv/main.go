@@ -39,10 +39,22 @@ func main() {
 	// 初始化命令
 	initCmd := cli.NewInitCommand(configFile)
 
+	// 题目管理命令
+	challengeCmd := cli.NewChallengeCommand(configFile)
+
+	// 题目同步命令
+	syncCmd := cli.NewSyncCommand(configFile)
+
+	// 独立网关命令
+	gatewayCmd := cli.NewGatewayCommand(configFile)
+
 	// 添加子命令
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(userCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(challengeCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(gatewayCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
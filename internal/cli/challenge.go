@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/spf13/cobra"
+)
+
+// NewChallengeCommand 创建题目管理命令
+func NewChallengeCommand(configFile string) *cobra.Command {
+	challengeCmd := &cobra.Command{
+		Use:   "challenge",
+		Short: "题目管理",
+		Long:  `导入导出题目包`,
+	}
+
+	challengeCmd.AddCommand(
+		newChallengeExportCommand(configFile),
+		newChallengeImportCommand(configFile),
+	)
+
+	return challengeCmd
+}
+
+// newChallengeExportCommand 导出题目包
+func newChallengeExportCommand(configFile string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <challenge-id> <output>",
+		Short: "导出题目包",
+		Long:  `导出题目为题目包，output 以 .zip 结尾时导出为压缩包，否则导出为目录`,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportChallenge(configFile, args[0], args[1])
+		},
+	}
+
+	return cmd
+}
+
+// newChallengeImportCommand 导入题目包
+func newChallengeImportCommand(configFile string) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "import <path>...",
+		Short: "导入题目包",
+		Long:  `从目录、.zip 或 .tar.gz 导入题目包，按 slug 新建或更新题目`,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importChallenges(configFile, args, dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只显示变更，不写入数据库")
+
+	return cmd
+}
+
+// exportChallenge 导出题目包
+func exportChallenge(configFile, id, output string) error {
+	container, err := initializeCyberDependency(configFile)
+	if err != nil {
+		return fmt.Errorf("初始化容器失败: %v", err)
+	}
+
+	b, err := container.ChallengeBundleService.Export(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("导出题目失败: %v", err)
+	}
+
+	if strings.HasSuffix(strings.ToLower(output), ".zip") {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := b.WriteZip(f); err != nil {
+			return fmt.Errorf("写入题目包失败: %v", err)
+		}
+	} else if err := b.WriteDir(output); err != nil {
+		return fmt.Errorf("写入题目包失败: %v", err)
+	}
+
+	fmt.Printf("成功导出题目 %s 到 %s\n", b.Manifest.Slug, output)
+	return nil
+}
+
+// importChallenges 逐个导入题目包，某个题目包失败不影响其他题目包
+func importChallenges(configFile string, paths []string, dryRun bool) error {
+	container, err := initializeCyberDependency(configFile)
+	if err != nil {
+		return fmt.Errorf("初始化容器失败: %v", err)
+	}
+
+	ctx := context.Background()
+	var failed int
+	for _, p := range paths {
+		if err := importChallenge(ctx, container.ChallengeBundleService, p, dryRun); err != nil {
+			fmt.Printf("导入 %s 失败: %v\n", p, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个题目包导入失败", failed)
+	}
+	return nil
+}
+
+func importChallenge(ctx context.Context, bundleService *service.ChallengeBundleService, p string, dryRun bool) error {
+	b, err := bundle.Load(p)
+	if err != nil {
+		return err
+	}
+
+	plan, err := bundleService.Import(ctx, b, dryRun, "")
+	if err != nil {
+		var ve *bundle.ValidationError
+		if errors.As(err, &ve) {
+			for _, fe := range ve.Errors {
+				fmt.Printf("  %s: %s\n", fe.Field, fe.Message)
+			}
+			return fmt.Errorf("题目包校验失败")
+		}
+		return err
+	}
+
+	printImportPlan(filepath.Base(p), plan, dryRun)
+	return nil
+}
+
+// printImportPlan 输出导入计划
+func printImportPlan(name string, plan *service.ImportPlan, dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "[dry-run] "
+	}
+	fmt.Printf("%s%s (%s): %s\n", prefix, plan.Slug, name, plan.Action)
+	if plan.Image == service.BundleActionCreate {
+		fmt.Printf("  新建镜像\n")
+	}
+	for _, change := range plan.Changes {
+		fmt.Printf("  %s: %q -> %q\n", change.Field, change.Old, change.New)
+	}
+	for _, attachment := range plan.Attachments {
+		if attachment.Action != service.BundleActionUnchanged {
+			fmt.Printf("  %s/%s: %s\n", bundle.AttachmentDir, attachment.Name, attachment.Action)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/go-orz/orz"
+	"github.com/spf13/cobra"
+)
+
+// NewGatewayCommand 创建网关命令
+func NewGatewayCommand(configFile string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gateway",
+		Short: "独立运行统一网关",
+		Long:  `只运行统一网关，路由从数据库中的实例表同步，可部署多个副本并挂在负载均衡之后`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGateway(configFile)
+		},
+	}
+
+	return cmd
+}
+
+// runGateway 启动独立网关
+func runGateway(configFile string) error {
+	framework, err := orz.NewFramework(
+		orz.WithConfig(configFile),
+		orz.WithLoggerFromConfig(),
+		orz.WithDatabase(),
+	)
+	if err != nil {
+		return fmt.Errorf("初始化框架失败: %v", err)
+	}
+
+	logger := framework.App().Logger()
+	db := framework.App().GetDatabase()
+
+	var conf config.Config
+	err = framework.App().GetConfig().App.Unmarshal(&conf)
+	if err != nil {
+		return fmt.Errorf("获取配置失败: %v", err)
+	}
+
+	reverseProxyService := service.NewReverseProxyService(logger, db, &conf)
+	gatewayTLSService := service.NewGatewayTLSService(&conf, reverseProxyService)
+	dnsService := service.NewDNSService(logger, &conf, reverseProxyService)
+	return cyber.StartGateway(context.Background(), logger, &conf, reverseProxyService, gatewayTLSService, dnsService)
+}
@@ -177,6 +177,11 @@ func initChallenges(configFile string) error {
 		fmt.Printf("成功导入题目: %s\n", challenge.Name)
 	}
 
+	// 为题目的类别创建类别记录
+	if err := container.CategoryService.Sync(ctx); err != nil {
+		return fmt.Errorf("同步题目类别失败: %v", err)
+	}
+
 	fmt.Printf("题目数据导入完成，共处理 %d 个题目\n", len(challenges))
 	return nil
 }
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/spf13/cobra"
+)
+
+// NewSyncCommand 从目录同步题目包
+func NewSyncCommand(configFile string) *cobra.Command {
+	var dryRun, prune, yes bool
+	cmd := &cobra.Command{
+		Use:   "sync <path>",
+		Short: "从目录同步题目",
+		Long: `递归查找目录（如题目仓库的 git checkout）下的全部题目包，按 slug 新建或更新镜像和题目。
+执行前先输出变更计划并确认；--prune 时停用不在目录中的、由题目包导入的题目`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return syncChallenges(configFile, args[0], dryRun, prune, yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只显示变更，不写入数据库")
+	cmd.Flags().BoolVar(&prune, "prune", false, "停用目录中已删除的题目")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "不确认直接执行")
+
+	return cmd
+}
+
+func syncChallenges(configFile, root string, dryRun, prune, yes bool) error {
+	dirs, err := bundle.Discover(root)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("%s 下没有题目包", root)
+	}
+	var (
+		bundles = make([]*bundle.Bundle, 0, len(dirs))
+		names   = make(map[string]string, len(dirs))
+	)
+	for _, dir := range dirs {
+		b, err := bundle.Load(dir)
+		if err != nil {
+			return fmt.Errorf("加载 %s 失败: %v", dir, err)
+		}
+		name, err := filepath.Rel(root, dir)
+		if err != nil {
+			name = dir
+		}
+		names[b.Manifest.Slug] = name
+		bundles = append(bundles, b)
+	}
+
+	container, err := initializeCyberDependency(configFile)
+	if err != nil {
+		return fmt.Errorf("初始化容器失败: %v", err)
+	}
+	bundleService := container.ChallengeBundleService
+
+	ctx := context.Background()
+	plan, err := bundleService.Sync(ctx, bundles, true, prune, "")
+	if err != nil {
+		return syncError(err)
+	}
+	printSyncPlan(plan, names)
+	if dryRun {
+		return nil
+	}
+	if !plan.Changed() {
+		fmt.Println("没有变更")
+		return nil
+	}
+	if !yes {
+		fmt.Print("确认执行以上变更吗？(y/N): ")
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	plan, err = bundleService.Sync(ctx, bundles, false, prune, "")
+	if err != nil {
+		return syncError(err)
+	}
+	fmt.Printf("同步完成：%d 个题目，停用 %d 个题目\n", len(plan.Challenges), len(plan.Disabled))
+	return nil
+}
+
+// syncError 输出题目包的校验错误
+func syncError(err error) error {
+	var ve *bundle.ValidationError
+	if errors.As(err, &ve) {
+		for _, fe := range ve.Errors {
+			fmt.Printf("  %s: %s\n", fe.Field, fe.Message)
+		}
+		return fmt.Errorf("题目包校验失败")
+	}
+	return err
+}
+
+// printSyncPlan 输出同步计划，未变更的题目不输出
+func printSyncPlan(plan *service.SyncPlan, names map[string]string) {
+	for _, item := range plan.Challenges {
+		if item.Action == service.BundleActionUnchanged && item.Image != service.BundleActionCreate {
+			continue
+		}
+		printImportPlan(names[item.Slug], item, true)
+	}
+	for _, item := range plan.Disabled {
+		fmt.Printf("[dry-run] %s: disable\n", item.Slug)
+	}
+}
@@ -57,7 +57,10 @@ func initializeCyberDependency(configFile string) (*cyber.Dependency, error) {
 		return nil, fmt.Errorf("获取配置失败: %v", err)
 	}
 
-	dependency := cyber.ProviderDependency(logger, db, &conf)
+	dependency, err := cyber.ProviderDependency(logger, db, &conf)
+	if err != nil {
+		return nil, fmt.Errorf("初始化依赖失败: %v", err)
+	}
 	return dependency, nil
 }
 
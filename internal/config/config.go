@@ -1,8 +1,17 @@
 package config
 
+import "fmt"
+
 type Config struct {
-	Gateway Gateway     `yaml:"gateway"`
-	Email   EmailConfig `yaml:"email"`
+	Gateway    Gateway          `yaml:"gateway"`
+	Email      EmailConfig      `yaml:"email"`
+	Attachment AttachmentConfig `yaml:"attachment"`
+	Challenge  ChallengeConfig  `yaml:"challenge"`
+	Image      ImageConfig      `yaml:"image"`
+	Health     HealthConfig     `yaml:"health"`
+	Submission SubmissionConfig `yaml:"submission"`
+
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"` // 可信反向代理的 IP 或 CIDR，只采用来自这些地址的 X-Forwarded-For，本机地址始终可信
 }
 
 type Docker struct {
@@ -12,8 +21,120 @@ type Gateway struct {
 	Enabled bool   `yaml:"enabled"` // 启用网关
 	Addr    string `yaml:"addr"`    // 网关地址
 
-	Domain string `yaml:"domain"` // 只用于展示
-	Https  bool   `yaml:"https"`  // 只用于展示,是否启用HTTPS
+	Standalone   bool   `yaml:"standalone"`                                 // 网关独立部署（cyberpoc gateway），主服务不再监听网关地址
+	UpstreamHost string `yaml:"upstream_host" mapstructure:"upstream_host"` // 网关访问容器端口使用的主机地址，默认 127.0.0.1
+	SyncInterval int    `yaml:"sync_interval" mapstructure:"sync_interval"` // 从数据库同步路由的间隔，单位：秒，默认 5
+
+	Mode   string     `yaml:"mode"`   // 路由方式 subdomain、path
+	Domain string     `yaml:"domain"` // 只用于展示
+	Https  bool       `yaml:"https"`  // 只用于展示,是否启用HTTPS
+	TLS    GatewayTLS `yaml:"tls"`    // 网关内置 TLS
+	DNS    GatewayDNS `yaml:"dns"`    // 网关内置 DNS
+}
+
+const (
+	GatewayModeSubdomain = "subdomain" // 按子域名路由 <subdomain>.<domain>
+	GatewayModePath      = "path"      // 按路径路由 <domain>/i/<subdomain>/，无需泛域名解析
+
+	GatewayPathPrefix = "/i/"
+)
+
+// PathMode 是否按路径路由
+func (g Gateway) PathMode() bool {
+	return g.Mode == GatewayModePath
+}
+
+// AccessUrl 实例的访问地址
+func (g Gateway) AccessUrl(subdomain string) string {
+	if g.PathMode() {
+		return fmt.Sprintf(`%s://%s%s%s/`, g.Scheme(), g.Domain, GatewayPathPrefix, subdomain)
+	}
+	return fmt.Sprintf(`%s://%s.%s`, g.Scheme(), subdomain, g.Domain)
+}
+
+// Upstream 容器端口对应的转发地址
+func (g Gateway) Upstream(port string) string {
+	host := g.UpstreamHost
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return host + ":" + port
+}
+
+// Scheme 实例访问地址使用的协议
+func (g Gateway) Scheme() string {
+	if g.Https || g.TLS.Enabled {
+		return "https"
+	}
+	return "http"
+}
+
+const (
+	TLSProviderFile    = "file"     // 从文件加载泛域名证书
+	TLSProviderLocalCA = "local-ca" // 使用本地 CA 按子域名签发证书
+	TLSProviderACME    = "acme"     // 使用 ACME 按子域名申请证书
+)
+
+// GatewayTLS 网关直接终止 TLS 的配置
+type GatewayTLS struct {
+	Enabled  bool   `yaml:"enabled"`                            // 是否由网关终止 TLS
+	Provider string `yaml:"provider"`                           // 证书来源 file、local-ca、acme
+	CertFile string `yaml:"cert_file" mapstructure:"cert_file"` // file: 证书文件
+	KeyFile  string `yaml:"key_file" mapstructure:"key_file"`   // file: 私钥文件
+	CADir    string `yaml:"ca_dir" mapstructure:"ca_dir"`       // local-ca: CA 证书和私钥的存放目录
+	Email    string `yaml:"email"`                              // acme: 账户邮箱
+	CacheDir string `yaml:"cache_dir" mapstructure:"cache_dir"` // acme: 证书缓存目录
+	CAServer string `yaml:"ca_server" mapstructure:"ca_server"` // acme: 目录地址，默认为 Let's Encrypt
+}
+
+// GatewayDNS 内置权威 DNS 的配置，只在实例存在时解析 <subdomain>.<domain>
+type GatewayDNS struct {
+	Enabled bool   `yaml:"enabled"` // 是否启用
+	Addr    string `yaml:"addr"`    // 监听地址，默认 :53
+	IPv4    string `yaml:"ipv4"`    // A 记录指向的网关地址
+	IPv6    string `yaml:"ipv6"`    // AAAA 记录指向的网关地址
+	TTL     int    `yaml:"ttl"`     // 记录的 TTL，单位：秒，默认 30
+}
+
+const (
+	StorageLocal = "local" // 本地文件系统
+)
+
+// AttachmentConfig 题目附件的存储和下载配置
+type AttachmentConfig struct {
+	Storage string `yaml:"storage"`                          // 存储后端，默认 local
+	Dir     string `yaml:"dir"`                              // local: 存储目录，默认 data/attachments
+	Secret  string `yaml:"secret"`                           // 下载链接签名密钥，未配置时生成并保存到 data/attachment.secret，多台机器部署时必须配置
+	Expires int    `yaml:"expires"`                          // 下载链接有效期，单位：秒，默认 300
+	MaxSize int64  `yaml:"max_size" mapstructure:"max_size"` // 单个附件大小上限，单位：MB，默认 100
+}
+
+// ChallengeConfig 题目管理配置
+type ChallengeConfig struct {
+	SyncRoot string `yaml:"sync_root" mapstructure:"sync_root"` // 管理端同步题目包时允许访问的根目录，为空时不开放管理端同步
+}
+
+// ImageConfig 镜像配置
+type ImageConfig struct {
+	BuildRoot string `yaml:"build_root" mapstructure:"build_root"` // 从服务器目录构建镜像时允许访问的根目录，为空时只能上传构建上下文
+}
+
+// HealthConfig 题目健康检查配置
+type HealthConfig struct {
+	Enabled      bool   `yaml:"enabled"`                                    // 是否定时检查
+	Interval     int    `yaml:"interval"`                                   // 检查间隔，单位：分钟，默认 30
+	Timeout      int    `yaml:"timeout"`                                    // 单次检查的超时时间，单位：秒，默认 120
+	DefaultImage string `yaml:"default_image" mapstructure:"default_image"` // 只配置检查脚本时使用的镜像，默认 python:3-alpine
+}
+
+// SubmissionConfig Flag 提交频率限制，超出限制后进入冷却，连续超出时冷却时间翻倍
+type SubmissionConfig struct {
+	UserLimit      int `yaml:"user_limit" mapstructure:"user_limit"`           // 同一用户在窗口期内的提交次数上限（所有题目），默认 30，小于 0 时不限制
+	ChallengeLimit int `yaml:"challenge_limit" mapstructure:"challenge_limit"` // 同一用户在同一题目窗口期内的提交次数上限，默认 10，小于 0 时不限制，题目可单独配置
+	IPLimit        int `yaml:"ip_limit" mapstructure:"ip_limit"`               // 同一 IP 在窗口期内的提交次数上限，默认 60，小于 0 时不限制
+	Window         int `yaml:"window"`                                         // 窗口期，单位：秒，默认 60
+	Cooldown       int `yaml:"cooldown"`                                       // 首次超出限制后的冷却时间，单位：秒，默认 30
+	MaxCooldown    int `yaml:"max_cooldown" mapstructure:"max_cooldown"`       // 冷却时间上限，单位：秒，默认 3600
 }
 
 type EmailConfig struct {
@@ -0,0 +1,567 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/pkg/flagmatch"
+	"github.com/dushixiang/cyberpoc/pkg/nostd"
+	"gopkg.in/yaml.v3"
+)
+
+// 题目包的目录结构：
+//
+//	challenge.yaml     题目定义
+//	description.md     题目描述（可选）
+//	attachments/       附件（可选）
+const (
+	ManifestFile    = "challenge.yaml"
+	DescriptionFile = "description.md"
+	AttachmentDir   = "attachments"
+
+	// MaxSize 单个题目包（压缩包或解压后）的大小上限
+	MaxSize = 512 << 20
+)
+
+var (
+	ErrTooLarge = errors.New("题目包超过大小限制")
+	slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	envPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ValidSlug slug 只能包含小写字母、数字、-、_，会用作导出的目录名和文件名
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// Manifest challenge.yaml 的内容
+type Manifest struct {
+	Slug        string    `yaml:"slug"`
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description,omitempty"` // 未提供 description.md 时使用
+	Category    string    `yaml:"category"`
+	Difficulty  string    `yaml:"difficulty"` // easy、medium、hard
+	Tags        []string  `yaml:"tags,omitempty"`
+	Points      int64     `yaml:"points"`               // 动态计分时为初始分值
+	ScoreType   string    `yaml:"score_type,omitempty"` // static、dynamic
+	Minimum     int64     `yaml:"minimum,omitempty"`    // dynamic: 最低分值
+	Decay       int64     `yaml:"decay,omitempty"`      // dynamic: 第 decay+1 位解题者起降到最低分值
+	BonusType   string    `yaml:"bonus_type,omitempty"` // 前 N 名解题奖励方式 points、percent
+	Bonuses     []int64   `yaml:"bonuses,omitempty"`    // 第 i 名的奖励
+	Flag        string    `yaml:"flag,omitempty"`
+	DynamicFlag bool      `yaml:"dynamic_flag"`
+	Enabled     bool      `yaml:"enabled"`
+	Duration    int       `yaml:"duration,omitempty"` // 单位：分钟
+	Html        string    `yaml:"html,omitempty"`
+	Sort        int64     `yaml:"sort,omitempty"`
+	Image       *ImageRef `yaml:"image,omitempty"`
+	Hints       []HintRef `yaml:"hints,omitempty"`
+	Flags       []FlagRef `yaml:"flags,omitempty"` // 分阶段 Flag，配置后忽略 flag 和 dynamic_flag
+
+	FlagMatch        string `yaml:"flag_match,omitempty"` // exact、case-insensitive、regex、normalized
+	FlagStripWrapper bool   `yaml:"flag_strip_wrapper,omitempty"`
+
+	Prerequisites       []string `yaml:"prerequisites,omitempty"` // 前置题目的 slug
+	UnlockScore         int64    `yaml:"unlock_score,omitempty"`  // 解锁所需的最低得分
+	UnlockCategory      string   `yaml:"unlock_category,omitempty"`
+	UnlockCategoryCount int64    `yaml:"unlock_category_count,omitempty"` // 解锁需要通关的 unlock_category 题目数量
+
+	CheckerImage  string `yaml:"checker_image,omitempty"`  // 健康检查器镜像
+	CheckerScript string `yaml:"checker_script,omitempty"` // 健康检查脚本，在检查器镜像中以 sh -c 执行
+
+	SubmitLimit int `yaml:"submit_limit,omitempty"` // 每个用户在窗口期内的提交次数上限，小于 0 时不限制
+}
+
+// FlagRef Flag 阶段
+type FlagRef struct {
+	Name     string `yaml:"name"`
+	Flag     string `yaml:"flag,omitempty"`
+	Dynamic  bool   `yaml:"dynamic,omitempty"`
+	Env      string `yaml:"env,omitempty"` // 动态Flag注入容器的环境变量名，默认 FLAG_<NAME>
+	Points   int64  `yaml:"points,omitempty"`
+	Optional bool   `yaml:"optional,omitempty"`
+
+	Match        string `yaml:"match,omitempty"` // exact、case-insensitive、regex、normalized
+	StripWrapper bool   `yaml:"strip_wrapper,omitempty"`
+}
+
+// HintRef 提示，按顺序排列
+type HintRef struct {
+	Content string `yaml:"content"`
+	Cost    int64  `yaml:"cost,omitempty"` // 解锁扣除的分数
+}
+
+// ImageRef 题目使用的镜像，按 registry 匹配已有镜像
+type ImageRef struct {
+	Name        string  `yaml:"name"`
+	Registry    string  `yaml:"registry"`
+	CpuLimit    float64 `yaml:"cpu_limit"`
+	MemoryLimit int64   `yaml:"memory_limit"` // 单位：MB
+	Exposed     string  `yaml:"exposed"`
+	Protocol    string  `yaml:"protocol,omitempty"` // http 或 https，为空时为 http
+}
+
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Bundle 题目包
+type Bundle struct {
+	Manifest    Manifest
+	Description string
+	Attachments []Attachment
+}
+
+// FieldError 字段校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validate 校验题目包，返回每个字段的错误
+func (b *Bundle) Validate() []FieldError {
+	var errs []FieldError
+	add := func(field, message string) {
+		errs = append(errs, FieldError{Field: field, Message: message})
+	}
+
+	m := b.Manifest
+	if m.Slug == "" {
+		add("slug", "不能为空")
+	} else if !slugPattern.MatchString(m.Slug) {
+		add("slug", "只能包含小写字母、数字、-、_，且不超过64个字符")
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		add("name", "不能为空")
+	}
+	if !slices.Contains(models.Difficulties, m.Difficulty) {
+		add("difficulty", "只能是 "+strings.Join(models.Difficulties, "、"))
+	}
+	for i, tag := range m.Tags {
+		if strings.TrimSpace(tag) == "" || utf8.RuneCountInString(tag) > 64 {
+			add(fmt.Sprintf("tags[%d]", i), "不能为空且不超过64个字符")
+		}
+	}
+	if m.Points < 0 {
+		add("points", "不能小于0")
+	}
+	switch m.ScoreType {
+	case "", "static":
+	case "dynamic":
+		if m.Decay <= 0 {
+			add("decay", "动态计分的衰减人数必须大于0")
+		}
+		if m.Minimum < 0 || m.Minimum > m.Points {
+			add("minimum", "不能小于0且不能大于初始分值")
+		}
+	default:
+		add("score_type", "只能是 static 或 dynamic")
+	}
+	switch m.BonusType {
+	case "", "points", "percent":
+	default:
+		add("bonus_type", "只能是 points 或 percent")
+	}
+	for i, bonus := range m.Bonuses {
+		if bonus < 0 {
+			add(fmt.Sprintf("bonuses[%d]", i), "不能小于0")
+		}
+	}
+	if m.Image != nil {
+		if m.Image.Registry == "" {
+			add("image.registry", "不能为空")
+		}
+		if m.Image.Exposed == "" {
+			add("image.exposed", "不能为空")
+		}
+		if m.Image.Protocol != "" && m.Image.Protocol != "http" && m.Image.Protocol != "https" {
+			add("image.protocol", "只能是 http 或 https")
+		}
+		if m.Image.CpuLimit < 0 {
+			add("image.cpu_limit", "不能小于0")
+		}
+		if m.Image.MemoryLimit < 0 {
+			add("image.memory_limit", "不能小于0")
+		}
+		if m.Duration <= 0 {
+			add("duration", "需要启动环境的题目必须设置持续时长")
+		}
+	} else if m.DynamicFlag {
+		add("dynamic_flag", "动态Flag需要配置镜像")
+	}
+	if m.Image == nil && (m.CheckerImage != "" || m.CheckerScript != "") {
+		add("checker_image", "健康检查器需要配置镜像")
+	}
+	if len(m.Flags) == 0 && !m.DynamicFlag && m.Flag == "" {
+		add("flag", "静态Flag不能为空")
+	}
+	if len(m.Flags) == 0 {
+		checkMatch("flag_match", m.FlagMatch, m.Flag, m.DynamicFlag, add)
+		if m.Image != nil && m.FlagMatch == flagmatch.Regex {
+			add("flag_match", "需要启动环境的题目会把 Flag 注入容器，不能使用正则匹配")
+		}
+	}
+	var stages = make(map[string]bool)
+	for i, flag := range m.Flags {
+		field := fmt.Sprintf("flags[%d]", i)
+		if strings.TrimSpace(flag.Name) == "" {
+			add(field+".name", "不能为空")
+		} else if stages[flag.Name] {
+			add(field+".name", "重复的阶段")
+		}
+		stages[flag.Name] = true
+		if flag.Dynamic {
+			if m.Image == nil {
+				add(field+".dynamic", "动态Flag需要配置镜像")
+			}
+		} else if flag.Flag == "" {
+			add(field+".flag", "静态Flag不能为空")
+		}
+		if flag.Env != "" && !envPattern.MatchString(flag.Env) {
+			add(field+".env", "只能包含字母、数字和下划线，且不能以数字开头")
+		}
+		if flag.Points < 0 {
+			add(field+".points", "不能小于0")
+		}
+		checkMatch(field+".match", flag.Match, flag.Flag, flag.Dynamic, add)
+	}
+	var prerequisites = make(map[string]bool)
+	for i, slug := range m.Prerequisites {
+		field := fmt.Sprintf("prerequisites[%d]", i)
+		switch {
+		case !slugPattern.MatchString(slug):
+			add(field, "只能包含小写字母、数字、-、_，且不超过64个字符")
+		case slug == m.Slug:
+			add(field, "不能依赖自身")
+		case prerequisites[slug]:
+			add(field, "重复的前置题目")
+		}
+		prerequisites[slug] = true
+	}
+	if m.UnlockScore < 0 {
+		add("unlock_score", "不能小于0")
+	}
+	if m.UnlockCategoryCount < 0 {
+		add("unlock_category_count", "不能小于0")
+	}
+	for i, hint := range m.Hints {
+		if strings.TrimSpace(hint.Content) == "" {
+			add(fmt.Sprintf("hints[%d].content", i), "不能为空")
+		}
+		if hint.Cost < 0 {
+			add(fmt.Sprintf("hints[%d].cost", i), "不能小于0")
+		}
+	}
+	var names = make(map[string]bool)
+	for _, attachment := range b.Attachments {
+		if names[attachment.Name] {
+			add(AttachmentDir+"/"+attachment.Name, "重复的附件")
+		}
+		names[attachment.Name] = true
+	}
+	return errs
+}
+
+// checkMatch 校验 Flag 匹配方式，动态Flag不能使用正则匹配
+func checkMatch(field, mode, flag string, dynamic bool, add func(field, message string)) {
+	if dynamic && mode == flagmatch.Regex {
+		add(field, "动态Flag不能使用正则匹配")
+		return
+	}
+	if err := flagmatch.Validate(mode, flag); err != nil {
+		add(field, err.Error())
+	}
+}
+
+// Load 从目录、.zip、.tar.gz 或 .tgz 加载题目包
+func Load(p string) (*Bundle, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		// 不允许通过符号链接读取题目包目录以外的文件
+		root, err := os.OpenRoot(p)
+		if err != nil {
+			return nil, err
+		}
+		defer root.Close()
+		return LoadFS(root.FS())
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadArchive(filepath.Base(p), f, info.Size())
+}
+
+// LoadArchive 按文件名后缀加载压缩包
+func LoadArchive(name string, r io.ReaderAt, size int64) (*Bundle, error) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		return LoadFS(zr)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return loadTarGz(io.NewSectionReader(r, 0, size))
+	default:
+		return nil, fmt.Errorf("不支持的题目包格式: %s", name)
+	}
+}
+
+// loadTarGz 解压到临时目录后加载
+func loadTarGz(r io.Reader) (*Bundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	dir, err := os.MkdirTemp("", "cyberpoc-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var total int64
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		total += header.Size
+		if total > MaxSize {
+			return nil, ErrTooLarge
+		}
+		target, err := nostd.SafePathJoin(dir, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(f, io.LimitReader(tr, header.Size))
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS 加载题目包，challenge.yaml 可以位于根目录或唯一的子目录中
+func LoadFS(fsys fs.FS) (*Bundle, error) {
+	root, err := findRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+	fsys, err = fs.Sub(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	data, err := readFile(fsys, ManifestFile, MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	total += int64(len(data))
+	var b Bundle
+	if err := yaml.Unmarshal(data, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", ManifestFile, err)
+	}
+
+	b.Description = b.Manifest.Description
+	description, err := readFile(fsys, DescriptionFile, MaxSize-total)
+	if err == nil {
+		b.Description = string(description)
+		total += int64(len(description))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	b.Manifest.Description = ""
+
+	err = fs.WalkDir(fsys, AttachmentDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == AttachmentDir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := readFile(fsys, p, MaxSize-total)
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		b.Attachments = append(b.Attachments, Attachment{
+			Name: strings.TrimPrefix(p, AttachmentDir+"/"),
+			Data: data,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// readFile 最多读取 limit 字节，超出时返回 ErrTooLarge；压缩包中声明的文件大小不可信，不能直接整体读取
+func readFile(fsys fs.FS, name string, limit int64) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+func findRoot(fsys fs.FS) (string, error) {
+	if _, err := fs.Stat(fsys, ManifestFile); err == nil {
+		return ".", nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		if _, err := fs.Stat(fsys, path.Join(dirs[0], ManifestFile)); err == nil {
+			return dirs[0], nil
+		}
+	}
+	return "", fmt.Errorf("未找到 %s", ManifestFile)
+}
+
+// IsBundleDir 目录下是否直接包含 challenge.yaml
+func IsBundleDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, ManifestFile))
+	return err == nil && !info.IsDir()
+}
+
+func (b *Bundle) marshalManifest() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(b.Manifest); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteDir 写出为目录
+func (b *Bundle) WriteDir(dir string) error {
+	manifest, err := b.marshalManifest()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), manifest, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, DescriptionFile), []byte(b.Description), 0644); err != nil {
+		return err
+	}
+	for _, attachment := range b.Attachments {
+		target, err := nostd.SafePathJoin(filepath.Join(dir, AttachmentDir), attachment.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, attachment.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteZip 写出为 zip 压缩包，所有文件位于 <slug>/ 目录下
+func (b *Bundle) WriteZip(w io.Writer) error {
+	manifest, err := b.marshalManifest()
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	root := b.Manifest.Slug
+	write := func(name string, data []byte) error {
+		fw, err := zw.Create(path.Join(root, name))
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	if err := write(ManifestFile, manifest); err != nil {
+		return err
+	}
+	if err := write(DescriptionFile, []byte(b.Description)); err != nil {
+		return err
+	}
+	for _, attachment := range b.Attachments {
+		if err := write(path.Join(AttachmentDir, attachment.Name), attachment.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ValidationError 题目包校验失败
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var messages []string
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return "题目包校验失败: " + strings.Join(messages, "; ")
+}
@@ -0,0 +1,136 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestZipRoundTrip(t *testing.T) {
+	b := &Bundle{
+		Manifest: Manifest{
+			Slug:       "easy-sqli",
+			Name:       "Easy SQL Injection",
+			Difficulty: "easy",
+			Tags:       []string{"sqli", "web"},
+			Points:     100,
+			Flag:       "flag{test}",
+			Image: &ImageRef{
+				Registry: "cyberpoc/easy-sqli:latest",
+				Exposed:  "80/tcp",
+			},
+			Duration: 30,
+		},
+		Description: "# Easy SQL Injection",
+		Attachments: []Attachment{{Name: "src/index.php", Data: []byte("<?php")}},
+	}
+	if errs := b.Validate(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadArchive("easy-sqli.zip", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Manifest.Slug != b.Manifest.Slug || loaded.Manifest.Image.Registry != b.Manifest.Image.Registry {
+		t.Fatalf("manifest mismatch: %+v", loaded.Manifest)
+	}
+	if loaded.Description != b.Description {
+		t.Fatalf("description mismatch: %q", loaded.Description)
+	}
+	if len(loaded.Attachments) != 1 || loaded.Attachments[0].Name != "src/index.php" {
+		t.Fatalf("attachments mismatch: %+v", loaded.Attachments)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b := &Bundle{Manifest: Manifest{Slug: "Bad Slug", DynamicFlag: true}}
+	var fields = make(map[string]bool)
+	for _, fe := range b.Validate() {
+		fields[fe.Field] = true
+	}
+	for _, field := range []string{"slug", "name", "dynamic_flag"} {
+		if !fields[field] {
+			t.Errorf("expected error on %s", field)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"web/sqli", "web/sqli/src", "pwn/stack", ".git/hooks", "docs"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{"web/sqli", "web/sqli/src", "pwn/stack", ".git/hooks"} {
+		if err := os.WriteFile(filepath.Join(root, dir, ManifestFile), []byte("slug: x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "pwn/stack"), filepath.Join(root, "web/sqli")}
+	if !slices.Equal(dirs, want) {
+		t.Fatalf("got %v, want %v", dirs, want)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	newBundle := func(slug string, prerequisites ...string) *Bundle {
+		return &Bundle{Manifest: Manifest{Slug: slug, Prerequisites: prerequisites}}
+	}
+	ordered, err := Order([]*Bundle{newBundle("c", "b", "external"), newBundle("a"), newBundle("b", "a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slugs []string
+	for _, b := range ordered {
+		slugs = append(slugs, b.Manifest.Slug)
+	}
+	if !slices.Equal(slugs, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected order %v", slugs)
+	}
+
+	if _, err := Order([]*Bundle{newBundle("a", "b"), newBundle("b", "a")}); err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if _, err := Order([]*Bundle{newBundle("a"), newBundle("a")}); err == nil {
+		t.Fatal("expected duplicate slug error")
+	}
+}
+
+func TestLoadSymlinkOutsideBundle(t *testing.T) {
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret")
+	dir := filepath.Join(root, "bundle")
+	if err := os.MkdirAll(secret, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secret, "passwd"), []byte("root"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte("slug: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, AttachmentDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Load(dir)
+	if err == nil && len(b.Attachments) > 0 {
+		t.Fatalf("attachments outside the bundle were loaded: %v", b.Attachments[0].Name)
+	}
+}
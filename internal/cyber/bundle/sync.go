@@ -0,0 +1,94 @@
+package bundle
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Discover 查找目录树下的全部题目包目录，跳过隐藏目录（如 .git），题目包目录内不再继续查找
+func Discover(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if IsBundleDir(p) {
+			dirs = append(dirs, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// Order 按前置题目排序，前置题目在前；slug 重复或存在循环依赖时返回错误，不在本批次内的前置题目忽略
+func Order(bundles []*Bundle) ([]*Bundle, error) {
+	var bySlug = make(map[string]*Bundle, len(bundles))
+	for _, b := range bundles {
+		if _, ok := bySlug[b.Manifest.Slug]; ok {
+			return nil, fmt.Errorf("slug 重复: %s", b.Manifest.Slug)
+		}
+		bySlug[b.Manifest.Slug] = b
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	var (
+		state   = make(map[string]int, len(bundles))
+		ordered = make([]*Bundle, 0, len(bundles))
+		visit   func(b *Bundle, path []string) error
+	)
+	visit = func(b *Bundle, path []string) error {
+		slug := b.Manifest.Slug
+		switch state[slug] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("前置题目存在循环依赖: %s", strings.Join(append(path, slug), " -> "))
+		}
+		state[slug] = visiting
+		for _, prerequisite := range b.Manifest.Prerequisites {
+			if dep, ok := bySlug[prerequisite]; ok {
+				if err := visit(dep, append(path, slug)); err != nil {
+					return err
+				}
+			}
+		}
+		state[slug] = visited
+		ordered = append(ordered, b)
+		return nil
+	}
+	for _, b := range bundles {
+		if err := visit(b, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// LoadAll 加载目录树下的全部题目包
+func LoadAll(root string) ([]*Bundle, error) {
+	dirs, err := Discover(root)
+	if err != nil {
+		return nil, err
+	}
+	var bundles = make([]*Bundle, 0, len(dirs))
+	for _, dir := range dirs {
+		b, err := Load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		bundles = append(bundles, b)
+	}
+	return bundles, nil
+}
@@ -2,9 +2,13 @@ package cyber
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 	"sync"
 
 	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
 	"github.com/dushixiang/cyberpoc/internal/cyber/handler"
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/service"
@@ -12,8 +16,10 @@ import (
 	"github.com/dushixiang/cyberpoc/internal/types"
 	"github.com/go-orz/orz"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 var _ types.SubApp = (*App)(nil)
@@ -31,20 +37,46 @@ func Instance() *App {
 }
 
 type Dependency struct {
-	ChallengeHandler *handler.ChallengeHandler
-	ImageHandler     *handler.ImageHandler
-	IndexHandler     *handler.IndexHandler
-	InstanceHandler  *handler.InstanceHandler
-	DashboardHandler *handler.DashboardHandler
-	SolveHandler     *handler.SolveHandler
-
-	ChallengeService       *service.ChallengeService
-	ChallengeRecordService *service.ChallengeRecordService
-	ImageService           *service.ImageService
-	InstanceService        *service.InstanceService
-	SolveService           *service.SolveService
-	RankService            *service.RankService
-	ReverseProxyService    *service.ReverseProxyService
+	ChallengeHandler    *handler.ChallengeHandler
+	ImageHandler        *handler.ImageHandler
+	IndexHandler        *handler.IndexHandler
+	InstanceHandler     *handler.InstanceHandler
+	DashboardHandler    *handler.DashboardHandler
+	SolveHandler        *handler.SolveHandler
+	GatewayHandler      *handler.GatewayHandler
+	AttachmentHandler   *handler.AttachmentHandler
+	HintHandler         *handler.HintHandler
+	FlagHandler         *handler.FlagHandler
+	AnnouncementHandler *handler.AnnouncementHandler
+	CategoryHandler     *handler.CategoryHandler
+	TagHandler          *handler.TagHandler
+	WriteupHandler      *handler.WriteupHandler
+
+	ChallengeFeedbackHandler *handler.ChallengeFeedbackHandler
+	ChallengeSyncHandler     *handler.ChallengeSyncHandler
+	HealthCheckHandler       *handler.HealthCheckHandler
+	SubmissionHandler        *handler.SubmissionHandler
+
+	ChallengeService         *service.ChallengeService
+	ChallengeRecordService   *service.ChallengeRecordService
+	ChallengeBundleService   *service.ChallengeBundleService
+	AttachmentService        *service.AttachmentService
+	HintService              *service.HintService
+	FlagService              *service.FlagService
+	AnnouncementService      *service.AnnouncementService
+	CategoryService          *service.CategoryService
+	TagService               *service.TagService
+	ChallengeRevisionService *service.ChallengeRevisionService
+	WriteupService           *service.WriteupService
+	ChallengeFeedbackService *service.ChallengeFeedbackService
+	HealthCheckService       *service.HealthCheckService
+	ImageService             *service.ImageService
+	InstanceService          *service.InstanceService
+	SolveService             *service.SolveService
+	RankService              *service.RankService
+	ReverseProxyService      *service.ReverseProxyService
+	GatewayTLSService        *service.GatewayTLSService
+	DNSService               *service.DNSService
 }
 
 type App struct {
@@ -55,22 +87,71 @@ func (a App) Configure(app *orz.App, conf *config.Config) error {
 	logger := app.Logger()
 	database := app.GetDatabase()
 	e := app.GetEcho()
-	a.Dependency = ProviderDependency(logger, database, conf)
+	dependency, err := ProviderDependency(logger, database, conf)
+	if err != nil {
+		logger.Fatal("invalid config", zap.Error(err))
+	}
+	a.Dependency = dependency
+
+	// 提交限流和提交记录使用 RealIP，只信任本机和配置的反向代理传入的 X-Forwarded-For
+	ipExtractor, err := trustedProxyExtractor(conf.TrustedProxies)
+	if err != nil {
+		logger.Fatal("invalid trusted_proxies", zap.Error(err))
+	}
+	e.IPExtractor = ipExtractor
+
+	// slug 改为唯一索引前，为未设置 slug 的题目使用 ID 作为 slug；旧版本没有 slug 列时先只加列，唯一索引由 AutoMigrate 创建
+	if database.Migrator().HasTable(&models.Challenge{}) {
+		if !database.Migrator().HasColumn(&models.Challenge{}, "Slug") {
+			if err := database.Migrator().AddColumn(&models.Challenge{}, "Slug"); err != nil {
+				logger.Fatal("add challenge slug column failed", zap.Error(err))
+			}
+		}
+		if err := database.Model(&models.Challenge{}).Where("slug = '' or slug is null").Update("slug", gorm.Expr("id")).Error; err != nil {
+			logger.Fatal("fill challenge slugs failed", zap.Error(err))
+		}
+		if database.Migrator().HasIndex(&models.Challenge{}, "idx_challenges_slug") {
+			if err := database.Migrator().DropIndex(&models.Challenge{}, "idx_challenges_slug"); err != nil {
+				logger.Fatal("drop challenge slug index failed", zap.Error(err))
+			}
+		}
+	}
 
 	// 迁移数据库
-	err := database.AutoMigrate(
+	err = database.AutoMigrate(
 		&models.Challenge{},
 		&models.ChallengeRecord{},
 		&models.Image{},
 		&models.Instance{},
 		&models.Solve{},
 		&models.Rank{},
+		&models.ChallengeAttachment{},
+		&models.ChallengeHint{},
+		&models.HintUnlock{},
+		&models.ChallengeFlag{},
+		&models.FlagCapture{},
+		&models.Announcement{},
+		&models.Category{},
+		&models.Tag{},
+		&models.ChallengeTag{},
+		&models.ChallengeRevision{},
+		&models.Writeup{},
+		&models.ChallengeFeedback{},
+		&models.HealthCheck{},
+		&models.Submission{},
 	)
 	if err != nil {
 		logger.Fatal("database auto migrate failed", zap.Error(err))
 	}
 
 	ctx := context.Background()
+	// 为题目已使用的类别补充类别记录
+	if err := a.Dependency.CategoryService.Sync(ctx); err != nil {
+		logger.Fatal("sync categories failed", zap.Error(err))
+	}
+	if err := a.Dependency.ImageService.FailInterruptedBuilds(ctx); err != nil {
+		logger.Fatal("reset interrupted image builds failed", zap.Error(err))
+	}
 	err = a.Dependency.InstanceService.ReStartContainers(ctx)
 	if err != nil {
 		logger.Fatal("restart containers failed", zap.Error(err))
@@ -82,18 +163,32 @@ func (a App) Configure(app *orz.App, conf *config.Config) error {
 	_, _ = c.AddFunc("*/10 * * * *", func() {
 		_ = a.Dependency.RankService.Recompute(ctx)
 	})
+	// 定时任务：每分钟为定时上线的题目发布公告
+	_, _ = c.AddFunc("* * * * *", func() {
+		if err := a.Dependency.AnnouncementService.AnnounceReleases(ctx); err != nil {
+			logger.Warn("announce releases failed", zap.Error(err))
+		}
+	})
+	// 定时任务：为配置了检查器的题目运行健康检查
+	if conf.Health.Enabled {
+		interval := conf.Health.Interval
+		if interval <= 0 {
+			interval = 30
+		}
+		_, _ = c.AddFunc(fmt.Sprintf("@every %dm", interval), func() {
+			if err := a.Dependency.HealthCheckService.CheckAll(ctx); err != nil {
+				logger.Warn("health check failed", zap.Error(err))
+			}
+		})
+	}
 	c.Start()
 
 	// 启动反向代理服务
 
-	if conf.Gateway.Enabled {
+	// 独立部署时由 cyberpoc gateway 提供网关
+	if conf.Gateway.Enabled && !conf.Gateway.Standalone {
 		go func() {
-			reverseProxy := echo.New()
-			reverseProxy.HideBanner = true
-			reverseProxy.Any("/*", echo.WrapHandler(a.Dependency.ReverseProxyService))
-
-			logger.Info("proxy server listening at", zap.String("addr", conf.Gateway.Addr))
-			err := reverseProxy.Start(conf.Gateway.Addr)
+			err := StartGateway(ctx, logger, conf, a.Dependency.ReverseProxyService, a.Dependency.GatewayTLSService, a.Dependency.DNSService)
 			if err != nil {
 				logger.Fatal("reverse proxy server", zap.Error(err))
 			}
@@ -106,17 +201,49 @@ func (a App) Configure(app *orz.App, conf *config.Config) error {
 		{
 			indexHandler := a.Dependency.IndexHandler
 			challenges.GET("/paging", indexHandler.ChallengePaging)
+			challenges.GET("/search", indexHandler.SearchChallenges)
 			challenges.GET("/:challenge_id", indexHandler.GetChallenge)
 			challenges.GET("/:challenge_id/rank", indexHandler.GetChallengeRank)
 			challenges.GET("/:challenge_id/instance", indexHandler.GetInstance)
 			challenges.POST("/:challenge_id/run", indexHandler.ChallengeRun, identity.Auth())
+			challenges.POST("/:challenge_id/start", indexHandler.StartChallenge, identity.Auth())
 			challenges.POST("/:challenge_id/destroy", indexHandler.DestroyInstance, identity.Auth())
 			challenges.POST("/:challenge_id/flag", indexHandler.SubmitFlag, identity.Auth())
+
+			attachmentHandler := a.Dependency.AttachmentHandler
+			challenges.GET("/:challenge_id/attachments/:attachment_id/url", attachmentHandler.SignedURL, identity.Auth())
+
+			hintHandler := a.Dependency.HintHandler
+			challenges.POST("/:challenge_id/hints/:hint_id/unlock", hintHandler.Unlock, identity.Auth())
+
+			writeupHandler := a.Dependency.WriteupHandler
+			challenges.GET("/:challenge_id/writeups", writeupHandler.List, identity.Auth())
+			challenges.GET("/:challenge_id/writeups/mine", writeupHandler.Mine, identity.Auth())
+			challenges.PUT("/:challenge_id/writeups/mine", writeupHandler.Submit, identity.Auth())
+
+			feedbackHandler := a.Dependency.ChallengeFeedbackHandler
+			challenges.GET("/:challenge_id/feedback/mine", feedbackHandler.Mine, identity.Auth())
+			challenges.PUT("/:challenge_id/feedback/mine", feedbackHandler.Submit, identity.Auth())
 		}
 	}
 
+	// 附件下载，通过签名校验
+	e.GET("/api/attachments/:id/download", a.Dependency.AttachmentHandler.Download)
+
+	// 网关本地 CA 根证书
+	e.GET("/api/gateway/ca.crt", a.Dependency.GatewayHandler.CACertificate)
+
+	// 公告
+	e.GET("/api/announcements", a.Dependency.AnnouncementHandler.List)
+
+	// 类别和标签
+	e.GET("/api/categories", a.Dependency.CategoryHandler.List)
+	e.GET("/api/tags", a.Dependency.TagHandler.List)
+
 	// 公共排行接口
 	e.GET("/api/ranks", a.Dependency.IndexHandler.GetRanks)
+	// 用户主页
+	e.GET("/api/users/:user_id/solves", a.Dependency.IndexHandler.GetUserSolves)
 	// 管理看板接口
 	e.GET("/api/admin/dashboard/stats", a.Dependency.DashboardHandler.Stats, identity.Admin())
 
@@ -146,6 +273,9 @@ func (a App) Configure(app *orz.App, conf *config.Config) error {
 			image.POST("/pull-all", imageHandler.PullAll)
 			image.POST("/:id/sync", imageHandler.Sync)
 			image.POST("/:id/pull", imageHandler.Pull)
+			image.POST("/:id/build", imageHandler.Build)
+			image.POST("/:id/build-path", imageHandler.BuildFromPath)
+			image.GET("/:id/build-log", imageHandler.BuildLog)
 			image.PUT("/:id", imageHandler.Update)
 			image.DELETE("/:id", imageHandler.Delete)
 			image.GET("/:id", imageHandler.Get)
@@ -162,13 +292,120 @@ func (a App) Configure(app *orz.App, conf *config.Config) error {
 		{
 			challengeHandler := a.Dependency.ChallengeHandler
 			challenges.GET("/paging", challengeHandler.Paging)
+			challenges.GET("/graph", challengeHandler.Graph)
+			challenges.GET("/upcoming", challengeHandler.Upcoming)
 			challenges.POST("", challengeHandler.Create)
 			challenges.PUT("/:id", challengeHandler.Update)
 			challenges.DELETE("/:id", challengeHandler.Delete)
 			challenges.GET("/:id", challengeHandler.Get)
 			challenges.POST("/sort", challengeHandler.Sort)
+			challenges.POST("/import", challengeHandler.Import, middleware.BodyLimit(fmt.Sprintf("%dB", bundle.MaxSize)))
+			challenges.GET("/:id/export", challengeHandler.Export)
+			challenges.POST("/sync", a.Dependency.ChallengeSyncHandler.Sync)
+			challenges.GET("/:id/revisions", challengeHandler.Revisions)
+			challenges.GET("/:id/revisions/diff", challengeHandler.RevisionDiff)
+			challenges.POST("/:id/revisions/:revision_id/restore", challengeHandler.RestoreRevision)
+
+			attachmentHandler := a.Dependency.AttachmentHandler
+			challenges.GET("/:id/attachments", attachmentHandler.List)
+			challenges.POST("/:id/attachments", attachmentHandler.Upload)
+			challenges.DELETE("/:id/attachments/:attachment_id", attachmentHandler.Delete)
+
+			hintHandler := a.Dependency.HintHandler
+			challenges.GET("/:id/hints", hintHandler.List)
+			challenges.POST("/:id/hints", hintHandler.Create)
+			challenges.PUT("/:id/hints/:hint_id", hintHandler.Update)
+			challenges.DELETE("/:id/hints/:hint_id", hintHandler.Delete)
+
+			flagHandler := a.Dependency.FlagHandler
+			challenges.GET("/:id/flags", flagHandler.List)
+			challenges.POST("/:id/flags", flagHandler.Create)
+			challenges.PUT("/:id/flags/:flag_id", flagHandler.Update)
+			challenges.DELETE("/:id/flags/:flag_id", flagHandler.Delete)
+
+			tagHandler := a.Dependency.TagHandler
+			challenges.GET("/:id/tags", tagHandler.GetChallengeTags)
+			challenges.PUT("/:id/tags", tagHandler.SetChallengeTags)
+
+			feedbackHandler := a.Dependency.ChallengeFeedbackHandler
+			challenges.GET("/:id/feedback/summary", feedbackHandler.Summary)
+
+			healthCheckHandler := a.Dependency.HealthCheckHandler
+			challenges.GET("/:id/health-checks", healthCheckHandler.List)
+			challenges.POST("/:id/health-checks", healthCheckHandler.Run)
+
+			challenges.GET("/:id/submissions/stats", a.Dependency.SubmissionHandler.Stats)
+		}
+
+		categories := admin.Group("/categories")
+		{
+			categoryHandler := a.Dependency.CategoryHandler
+			categories.GET("", categoryHandler.List)
+			categories.POST("", categoryHandler.Create)
+			categories.PUT("/:id", categoryHandler.Update)
+			categories.DELETE("/:id", categoryHandler.Delete)
+		}
+
+		tags := admin.Group("/tags")
+		{
+			tagHandler := a.Dependency.TagHandler
+			tags.GET("", tagHandler.List)
+			tags.DELETE("/:id", tagHandler.Delete)
+		}
+
+		writeups := admin.Group("/writeups")
+		{
+			writeupHandler := a.Dependency.WriteupHandler
+			writeups.GET("/paging", writeupHandler.Paging)
+			writeups.POST("/:id/approve", writeupHandler.Approve)
+			writeups.POST("/:id/reject", writeupHandler.Reject)
+			writeups.DELETE("/:id", writeupHandler.Delete)
+		}
+
+		admin.GET("/health-checks/latest", a.Dependency.HealthCheckHandler.Latest)
+
+		feedback := admin.Group("/feedback")
+		{
+			feedbackHandler := a.Dependency.ChallengeFeedbackHandler
+			feedback.GET("/paging", feedbackHandler.Paging)
+			feedback.DELETE("/:id", feedbackHandler.Delete)
+		}
+
+		submissions := admin.Group("/submissions")
+		{
+			submissionHandler := a.Dependency.SubmissionHandler
+			submissions.GET("/paging", submissionHandler.Paging)
+			submissions.GET("/export", submissionHandler.Export)
 		}
 	}
 
 	return nil
 }
+
+// trustedProxyExtractor 只采用来自本机和可信代理的 X-Forwarded-For，proxies 为 IP 或 CIDR
+func trustedProxyExtractor(proxies []string) (echo.IPExtractor, error) {
+	var options = []echo.TrustOption{
+		echo.TrustLoopback(true),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address: %s", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			proxy = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,49 @@
+package cyber
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// StartGateway 启动统一网关，路由定时从数据库同步，开启 TLS 时由网关直接终止 TLS
+func StartGateway(ctx context.Context, logger *zap.Logger, conf *config.Config,
+	reverseProxyService *service.ReverseProxyService, gatewayTLSService *service.GatewayTLSService, dnsService *service.DNSService) error {
+	go reverseProxyService.StartSync(ctx)
+
+	if conf.Gateway.DNS.Enabled {
+		go func() {
+			err := dnsService.ListenAndServe()
+			if err != nil {
+				logger.Fatal("dns server", zap.Error(err))
+			}
+		}()
+	}
+
+	reverseProxy := echo.New()
+	reverseProxy.HideBanner = true
+	reverseProxy.HidePort = true
+	reverseProxy.Any("/*", echo.WrapHandler(reverseProxyService))
+
+	server := &http.Server{
+		Addr: conf.Gateway.Addr,
+	}
+	if conf.Gateway.TLS.Enabled {
+		tlsConfig, err := gatewayTLSService.TLSConfig()
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = tlsConfig
+	}
+
+	logger.Info("proxy server listening at",
+		zap.String("addr", conf.Gateway.Addr),
+		zap.Bool("tls", conf.Gateway.TLS.Enabled),
+		zap.String("provider", conf.Gateway.TLS.Provider),
+	)
+	return reverseProxy.StartServer(server)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type AnnouncementHandler struct {
+	announcementService *service.AnnouncementService
+}
+
+func NewAnnouncementHandler(announcementService *service.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		announcementService: announcementService,
+	}
+}
+
+// List 最新的公告
+func (r AnnouncementHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	items, err := r.announcementService.FindLatest(ctx, 20)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
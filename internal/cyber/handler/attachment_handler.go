@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+	challengeService  *service.ChallengeService
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService, challengeService *service.ChallengeService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		challengeService:  challengeService,
+	}
+}
+
+// List 题目的附件列表及下载次数
+func (r AttachmentHandler) List(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	items, err := r.attachmentService.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	downloadCount, err := r.attachmentService.SumDownloadCountByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"items":          items,
+		"download_count": downloadCount,
+	})
+}
+
+// Upload 上传附件，表单字段 file，可通过 name 指定文件名
+func (r AttachmentHandler) Upload(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	exists, err := r.challengeService.ExistsById(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrChallengeNotFound
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return xe.ErrInvalidParams
+	}
+	name := c.FormValue("name")
+	if name == "" {
+		name = fh.Filename
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	item, err := r.attachmentService.Upload(ctx, challengeId, name, f)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+func (r AttachmentHandler) Delete(c echo.Context) error {
+	id := c.Param("attachment_id")
+	ctx := c.Request().Context()
+	attachment, exists, err := r.attachmentService.FindByIdExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists || attachment.ChallengeId != c.Param("id") {
+		return xe.ErrAttachmentNotFound
+	}
+	return r.attachmentService.Delete(ctx, id)
+}
+
+// SignedURL 为玩家生成附件的临时下载地址
+func (r AttachmentHandler) SignedURL(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	id := c.Param("attachment_id")
+	ctx := c.Request().Context()
+
+	challenge, exists, err := r.challengeService.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
+		return xe.ErrChallengeNotFound
+	}
+	if err := r.challengeService.CheckUnlocked(ctx, identity.AccountId(c), challenge); err != nil {
+		return err
+	}
+	attachment, exists, err := r.attachmentService.FindByIdExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists || attachment.ChallengeId != challengeId {
+		return xe.ErrAttachmentNotFound
+	}
+
+	url, expiresAt := r.attachmentService.SignedURL(id)
+	return orz.Ok(c, orz.Map{
+		"url":        url,
+		"expires_at": expiresAt,
+	})
+}
+
+// Download 通过签名地址下载附件，无需登录
+func (r AttachmentHandler) Download(c echo.Context) error {
+	id := c.Param("id")
+	err := r.attachmentService.VerifySignature(id, c.QueryParam("expires"), c.QueryParam("signature"))
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	attachment, rc, err := r.attachmentService.Open(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// 断点续传的后续分片不重复计数
+	if c.Request().Header.Get("Range") == "" {
+		if err := r.attachmentService.IncrDownloadCount(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	filename := path.Base(attachment.Name)
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, c.Request(), filename, time.UnixMilli(attachment.UpdatedAt), rc)
+	return nil
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// List 全部类别，按 sort 排序
+func (r CategoryHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	items, err := r.categoryService.FindAllSorted(ctx)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+func (r CategoryHandler) Create(c echo.Context) error {
+	var item models.Category
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if err := r.categoryService.Create(ctx, &item); err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+func (r CategoryHandler) Update(c echo.Context) error {
+	var item models.Category
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	item.ID = c.Param("id")
+	ctx := c.Request().Context()
+	return r.categoryService.Update(ctx, item)
+}
+
+func (r CategoryHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	return r.categoryService.Delete(ctx, id)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type ChallengeFeedbackHandler struct {
+	challengeFeedbackService *service.ChallengeFeedbackService
+}
+
+func NewChallengeFeedbackHandler(challengeFeedbackService *service.ChallengeFeedbackService) *ChallengeFeedbackHandler {
+	return &ChallengeFeedbackHandler{
+		challengeFeedbackService: challengeFeedbackService,
+	}
+}
+
+// Mine 自己对题目的评价，未评价时返回空对象
+func (r ChallengeFeedbackHandler) Mine(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	ctx := c.Request().Context()
+	item, exists, err := r.challengeFeedbackService.Mine(ctx, challengeId, identity.AccountId(c))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return orz.Ok(c, orz.Map{})
+	}
+	return orz.Ok(c, item)
+}
+
+// Submit 提交或修改评价：评分 1-5、体感难度和评论
+func (r ChallengeFeedbackHandler) Submit(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	var req struct {
+		Rating     int    `json:"rating"`
+		Difficulty string `json:"difficulty"`
+		Comment    string `json:"comment"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	item, err := r.challengeFeedbackService.Submit(ctx, challengeId, identity.AccountId(c), req.Rating, req.Difficulty, req.Comment)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+// Paging 管理端分页查询评价，可按题目过滤
+func (r ChallengeFeedbackHandler) Paging(c echo.Context) error {
+	pageIndex, pageSize := pageParams(c)
+	ctx := c.Request().Context()
+	items, total, err := r.challengeFeedbackService.Paging(ctx, (pageIndex-1)*pageSize, pageSize, c.QueryParam("challengeId"))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"items": items,
+		"total": total,
+	})
+}
+
+// Summary 管理端查看题目的评价汇总
+func (r ChallengeFeedbackHandler) Summary(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	summary, err := r.challengeFeedbackService.Summary(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, summary)
+}
+
+func (r ChallengeFeedbackHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	return r.challengeFeedbackService.DeleteById(ctx, id)
+}
@@ -1,22 +1,54 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/richtext"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
 type ChallengeHandler struct {
-	challengeService       *service.ChallengeService
-	challengeRecordService *service.ChallengeRecordService
+	challengeService         *service.ChallengeService
+	challengeRecordService   *service.ChallengeRecordService
+	challengeBundleService   *service.ChallengeBundleService
+	imageService             *service.ImageService
+	attachmentService        *service.AttachmentService
+	hintService              *service.HintService
+	flagService              *service.FlagService
+	tagService               *service.TagService
+	challengeRevisionService *service.ChallengeRevisionService
+	writeupService           *service.WriteupService
+	challengeFeedbackService *service.ChallengeFeedbackService
 }
 
-func NewChallengeHandler(challengeService *service.ChallengeService, challengeRecordService *service.ChallengeRecordService) *ChallengeHandler {
+func NewChallengeHandler(challengeService *service.ChallengeService, challengeRecordService *service.ChallengeRecordService, challengeBundleService *service.ChallengeBundleService,
+	imageService *service.ImageService, attachmentService *service.AttachmentService, hintService *service.HintService, flagService *service.FlagService, tagService *service.TagService,
+	challengeRevisionService *service.ChallengeRevisionService, writeupService *service.WriteupService,
+	challengeFeedbackService *service.ChallengeFeedbackService) *ChallengeHandler {
 	return &ChallengeHandler{
-		challengeService:       challengeService,
-		challengeRecordService: challengeRecordService,
+		challengeService:         challengeService,
+		challengeRecordService:   challengeRecordService,
+		challengeBundleService:   challengeBundleService,
+		imageService:             imageService,
+		attachmentService:        attachmentService,
+		hintService:              hintService,
+		flagService:              flagService,
+		tagService:               tagService,
+		challengeRevisionService: challengeRevisionService,
+		writeupService:           writeupService,
+		challengeFeedbackService: challengeFeedbackService,
 	}
 }
 
@@ -76,10 +108,36 @@ func (r ChallengeHandler) Create(c echo.Context) error {
 	if err := c.Validate(&item); err != nil {
 		return err
 	}
+	if err := r.challengeService.CheckScoring(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckBonus(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckFlagMatch(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckImage(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckPrerequisites(c.Request().Context(), item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckClassification(c.Request().Context(), item); err != nil {
+		return err
+	}
 
 	item.ID = uuid.NewString()
-	ctx := c.Request().Context()
-	return r.challengeService.Create(ctx, &item)
+	if err := r.challengeService.CheckSlug(c.Request().Context(), item); err != nil {
+		return err
+	}
+	item.Html = richtext.Sanitize(item.Html)
+	return r.challengeService.Transaction(c.Request().Context(), func(ctx context.Context) error {
+		if err := r.challengeService.Create(ctx, &item); err != nil {
+			return err
+		}
+		return r.challengeRevisionService.Record(ctx, item.ID, identity.AccountId(c), models.RevisionActionCreate, nil)
+	})
 }
 
 func (r ChallengeHandler) Get(c echo.Context) error {
@@ -99,16 +157,189 @@ func (r ChallengeHandler) Update(c echo.Context) error {
 		return err
 	}
 	item.ID = id
+	if err := r.challengeService.CheckScoring(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckBonus(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckFlagMatch(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckImage(item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckPrerequisites(c.Request().Context(), item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckClassification(c.Request().Context(), item); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckSlug(c.Request().Context(), item); err != nil {
+		return err
+	}
 
+	item.Html = richtext.Sanitize(item.Html)
+
+	return r.challengeService.Transaction(c.Request().Context(), func(ctx context.Context) error {
+		before, exists, err := r.challengeService.FindByIdExists(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return xe.ErrChallengeNotFound
+		}
+		if err := r.challengeService.UpdateById(ctx, &item); err != nil {
+			return err
+		}
+		if err := r.challengeService.SyncScoreType(ctx, before); err != nil {
+			return err
+		}
+		return r.challengeRevisionService.Record(ctx, id, identity.AccountId(c), models.RevisionActionUpdate, &before)
+	})
+}
+
+// Revisions 题目的修订记录
+func (r ChallengeHandler) Revisions(c echo.Context) error {
+	id := c.Param("id")
 	ctx := c.Request().Context()
+	items, err := r.challengeRevisionService.FindByChallengeId(ctx, id)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
 
-	return r.challengeService.UpdateById(ctx, &item)
+// RevisionDiff 比较两个修订，?from=&to= 为修订ID
+func (r ChallengeHandler) RevisionDiff(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	changes, err := r.challengeRevisionService.Diff(ctx, id, c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, changes)
 }
 
-func (r ChallengeHandler) Delete(c echo.Context) error {
+// RestoreRevision 把题目恢复到某个修订，恢复本身也会记录一个新的修订
+func (r ChallengeHandler) RestoreRevision(c echo.Context) error {
 	id := c.Param("id")
 	ctx := c.Request().Context()
-	return r.challengeService.DeleteById(ctx, id)
+	challenge, err := r.challengeRevisionService.Snapshot(ctx, id, c.Param("revision_id"))
+	if err != nil {
+		return err
+	}
+	challenge.Html = richtext.Sanitize(challenge.Html)
+	// slug 改为唯一索引前的快照可能没有 slug
+	if challenge.Slug == "" {
+		challenge.Slug = challenge.ID
+	}
+	// 快照可能引用已删除的镜像、类别或前置题目，按修改题目时的规则重新校验
+	if err := r.challengeService.CheckScoring(challenge); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckBonus(challenge); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckFlagMatch(challenge); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckImage(challenge); err != nil {
+		return err
+	}
+	if challenge.ImageId != "" {
+		exists, err := r.imageService.ExistsById(ctx, challenge.ImageId)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return xe.ErrImageNotFound
+		}
+	}
+	if err := r.challengeService.CheckPrerequisites(ctx, challenge); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckClassification(ctx, challenge); err != nil {
+		return err
+	}
+	if err := r.challengeService.CheckSlug(ctx, challenge); err != nil {
+		return err
+	}
+
+	return r.challengeService.Transaction(ctx, func(ctx context.Context) error {
+		before, exists, err := r.challengeService.FindByIdExists(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := r.challengeService.Save(ctx, &challenge); err != nil {
+			return err
+		}
+		var previous *models.Challenge
+		if exists {
+			previous = &before
+			if err := r.challengeService.SyncScoreType(ctx, before); err != nil {
+				return err
+			}
+		}
+		return r.challengeRevisionService.Record(ctx, id, identity.AccountId(c), models.RevisionActionRestore, previous)
+	})
+}
+
+// Graph 题目依赖图，包含循环依赖
+func (r ChallengeHandler) Graph(c echo.Context) error {
+	ctx := c.Request().Context()
+	graph, err := r.challengeService.Graph(ctx)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, graph)
+}
+
+// Upcoming 尚未上线的题目，按上线时间排序
+func (r ChallengeHandler) Upcoming(c echo.Context) error {
+	ctx := c.Request().Context()
+	items, err := r.challengeService.FindUpcoming(ctx, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+func (r ChallengeHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+	var keys []string
+	err := r.challengeService.Transaction(c.Request().Context(), func(ctx context.Context) error {
+		if err := r.challengeService.DeleteById(ctx, id); err != nil {
+			return err
+		}
+		if err := r.challengeService.RemovePrerequisite(ctx, id); err != nil {
+			return err
+		}
+		if err := r.hintService.DeleteByChallengeId(ctx, id); err != nil {
+			return err
+		}
+		if err := r.flagService.ReplaceByChallengeId(ctx, id, nil); err != nil {
+			return err
+		}
+		if err := r.tagService.DeleteByChallengeId(ctx, id); err != nil {
+			return err
+		}
+		if err := r.writeupService.DeleteByChallengeId(ctx, id); err != nil {
+			return err
+		}
+		if err := r.challengeFeedbackService.DeleteByChallengeId(ctx, id); err != nil {
+			return err
+		}
+		var err error
+		keys, err = r.attachmentService.DeleteByChallengeId(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// 附件文件在事务提交后再删除，回滚时文件仍然可用
+	r.attachmentService.Purge(c.Request().Context(), keys...)
+	return nil
 }
 
 func (r ChallengeHandler) ChallengeRecordPaging(c echo.Context) error {
@@ -128,3 +359,62 @@ func (r ChallengeHandler) ChallengeRecordPaging(c echo.Context) error {
 		"total": page.Total,
 	})
 }
+
+// Export 导出题目包（zip）
+func (r ChallengeHandler) Export(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	b, err := r.challengeBundleService.Export(ctx, id)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := b.WriteZip(&buf); err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, b.Manifest.Slug))
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// Import 导入题目包，支持 zip、tar.gz，dry_run=true 时只返回变更计划
+func (r ChallengeHandler) Import(c echo.Context) error {
+	dryRun := c.QueryParam("dry_run") == "true"
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return xe.ErrInvalidParams
+	}
+	if fh.Size > bundle.MaxSize {
+		return xe.ErrInvalidBundle
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := bundle.LoadArchive(fh.Filename, f, fh.Size)
+	if err != nil {
+		return xe.ErrInvalidBundle
+	}
+
+	ctx := c.Request().Context()
+	plan, err := r.challengeBundleService.Import(ctx, b, dryRun, identity.AccountId(c))
+	if err != nil {
+		var ve *bundle.ValidationError
+		if errors.As(err, &ve) {
+			return c.JSON(http.StatusBadRequest, orz.Map{
+				"code":    xe.ErrInvalidBundle.Code,
+				"message": xe.ErrInvalidBundle.Error(),
+				"errors":  ve.Errors,
+			})
+		}
+		return err
+	}
+
+	// 新建的镜像自动拉取
+	if !dryRun && plan.Image == service.BundleActionCreate {
+		go func() {
+			_ = r.imageService.Pull(context.Background(), plan.ImageId)
+		}()
+	}
+	return orz.Ok(c, plan)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type ChallengeSyncHandler struct {
+	conf                   *config.Config
+	challengeBundleService *service.ChallengeBundleService
+	imageService           *service.ImageService
+}
+
+func NewChallengeSyncHandler(conf *config.Config, challengeBundleService *service.ChallengeBundleService, imageService *service.ImageService) *ChallengeSyncHandler {
+	return &ChallengeSyncHandler{
+		conf:                   conf,
+		challengeBundleService: challengeBundleService,
+		imageService:           imageService,
+	}
+}
+
+// Sync 从同步根目录下的目录同步题目包，path 为相对于根目录的路径，dry_run 时只返回计划
+func (r ChallengeSyncHandler) Sync(c echo.Context) error {
+	root := r.conf.Challenge.SyncRoot
+	if root == "" {
+		return xe.ErrSyncDisabled
+	}
+	var req struct {
+		Path   string `json:"path"`
+		DryRun bool   `json:"dry_run"`
+		Prune  bool   `json:"prune"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	// 限制在根目录内
+	rel := filepath.Clean("/" + req.Path)
+	dir := filepath.Join(root, rel)
+	// 只同步子目录时无法判断其他题目是否已删除
+	if req.Prune && rel != "/" {
+		return xe.ErrPruneSubPath
+	}
+
+	bundles, err := bundle.LoadAll(dir)
+	if err != nil {
+		return xe.ErrInvalidBundle
+	}
+
+	ctx := c.Request().Context()
+	plan, err := r.challengeBundleService.Sync(ctx, bundles, req.DryRun, req.Prune, identity.AccountId(c))
+	if err != nil {
+		var ve *bundle.ValidationError
+		if errors.As(err, &ve) {
+			return c.JSON(http.StatusBadRequest, orz.Map{
+				"code":    xe.ErrInvalidBundle.Code,
+				"message": xe.ErrInvalidBundle.Error(),
+				"errors":  ve.Errors,
+			})
+		}
+		return err
+	}
+
+	// 新建的镜像自动拉取
+	if !req.DryRun {
+		for _, item := range plan.Challenges {
+			if item.Image != service.BundleActionCreate {
+				continue
+			}
+			imageId := item.ImageId
+			go func() {
+				_ = r.imageService.Pull(context.Background(), imageId)
+			}()
+		}
+	}
+	return orz.Ok(c, plan)
+}
@@ -11,10 +11,13 @@ type DashboardHandler struct {
 	challengeService *service.ChallengeService
 	instanceService  *service.InstanceService
 	solveService     *service.SolveService
+
+	healthCheckService *service.HealthCheckService
 }
 
-func NewDashboardHandler(challengeService *service.ChallengeService, instanceService *service.InstanceService, solveService *service.SolveService) *DashboardHandler {
-	return &DashboardHandler{challengeService: challengeService, instanceService: instanceService, solveService: solveService}
+func NewDashboardHandler(challengeService *service.ChallengeService, instanceService *service.InstanceService, solveService *service.SolveService,
+	healthCheckService *service.HealthCheckService) *DashboardHandler {
+	return &DashboardHandler{challengeService: challengeService, instanceService: instanceService, solveService: solveService, healthCheckService: healthCheckService}
 }
 
 func (h DashboardHandler) Stats(c echo.Context) error {
@@ -25,10 +28,16 @@ func (h DashboardHandler) Stats(c echo.Context) error {
 	var running int64
 	_ = h.instanceService.InstanceRepo.GetDB(ctx).Model(&models.Instance{}).Where("status = ?", models.InstanceStatusRunning).Count(&running).Error
 	solveCount, _ := h.solveService.Repository.Count(ctx)
+	// 最近一次健康检查未通过的题目
+	unhealthy, err := h.healthCheckService.FindLatest(ctx, true)
+	if err != nil {
+		return err
+	}
 	return orz.Ok(c, orz.Map{
-		"challenges":        challengeCount,
-		"instances":         instanceCount,
-		"running_instances": running,
-		"solves":            solveCount,
+		"challenges":           challengeCount,
+		"instances":            instanceCount,
+		"running_instances":    running,
+		"solves":               solveCount,
+		"unhealthy_challenges": unhealthy,
 	})
 }
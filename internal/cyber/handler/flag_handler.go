@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type FlagHandler struct {
+	flagService      *service.FlagService
+	challengeService *service.ChallengeService
+}
+
+func NewFlagHandler(flagService *service.FlagService, challengeService *service.ChallengeService) *FlagHandler {
+	return &FlagHandler{
+		flagService:      flagService,
+		challengeService: challengeService,
+	}
+}
+
+func (r FlagHandler) List(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	items, err := r.flagService.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+func (r FlagHandler) Create(c echo.Context) error {
+	challengeId := c.Param("id")
+	var item models.ChallengeFlag
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	if err := r.flagService.CheckStage(item); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if err := r.checkChallenge(ctx, challengeId, item); err != nil {
+		return err
+	}
+
+	item.ID = uuid.NewString()
+	item.ChallengeId = challengeId
+	if err := r.flagService.Create(ctx, &item); err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+func (r FlagHandler) Update(c echo.Context) error {
+	challengeId := c.Param("id")
+	id := c.Param("flag_id")
+	var item models.ChallengeFlag
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	if err := r.flagService.CheckStage(item); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if err := r.checkChallenge(ctx, challengeId, item); err != nil {
+		return err
+	}
+	if err := r.checkOwner(ctx, challengeId, id); err != nil {
+		return err
+	}
+	return r.flagService.UpdateColumnsById(ctx, id, orz.Map{
+		"name":          item.Name,
+		"flag":          item.Flag,
+		"dynamic":       item.Dynamic,
+		"env":           item.Env,
+		"points":        item.Points,
+		"optional":      item.Optional,
+		"match":         item.Match,
+		"strip_wrapper": item.StripWrapper,
+		"sort":          item.Sort,
+	})
+}
+
+// checkChallenge 题目需要存在，动态阶段需要题目配置了镜像
+func (r FlagHandler) checkChallenge(ctx context.Context, challengeId string, item models.ChallengeFlag) error {
+	challenge, exists, err := r.challengeService.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrChallengeNotFound
+	}
+	if item.Dynamic && !challenge.RequiresInstance() {
+		return xe.ErrDynamicFlagNoImage
+	}
+	return nil
+}
+
+// checkOwner 阶段需要属于该题目
+func (r FlagHandler) checkOwner(ctx context.Context, challengeId, id string) error {
+	stage, exists, err := r.flagService.FindByIdExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists || stage.ChallengeId != challengeId {
+		return xe.ErrFlagStageNotFound
+	}
+	return nil
+}
+
+func (r FlagHandler) Delete(c echo.Context) error {
+	id := c.Param("flag_id")
+	ctx := c.Request().Context()
+	if err := r.checkOwner(ctx, c.Param("id"), id); err != nil {
+		return err
+	}
+	return r.flagService.DeleteById(ctx, id)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/labstack/echo/v4"
+)
+
+func NewGatewayHandler(conf *config.Config, gatewayTLSService *service.GatewayTLSService) *GatewayHandler {
+	return &GatewayHandler{
+		conf:              conf,
+		gatewayTLSService: gatewayTLSService,
+	}
+}
+
+type GatewayHandler struct {
+	conf              *config.Config
+	gatewayTLSService *service.GatewayTLSService
+}
+
+// CACertificate 下载本地 CA 根证书，用户导入信任后即可访问网关签发的证书
+func (h GatewayHandler) CACertificate(c echo.Context) error {
+	tlsConf := h.conf.Gateway.TLS
+	if !tlsConf.Enabled || tlsConf.Provider != config.TLSProviderLocalCA {
+		return echo.NewHTTPError(http.StatusNotFound, "local ca is not enabled")
+	}
+	ca, err := h.gatewayTLSService.LocalCA()
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="cyberpoc-ca.crt"`)
+	return c.Blob(http.StatusOK, "application/x-x509-ca-cert", ca.CertificatePEM())
+}
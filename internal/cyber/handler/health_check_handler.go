@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type HealthCheckHandler struct {
+	healthCheckService *service.HealthCheckService
+}
+
+func NewHealthCheckHandler(healthCheckService *service.HealthCheckService) *HealthCheckHandler {
+	return &HealthCheckHandler{
+		healthCheckService: healthCheckService,
+	}
+}
+
+// List 题目最近的健康检查记录
+func (r HealthCheckHandler) List(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	items, err := r.healthCheckService.FindByChallengeId(ctx, challengeId, 50)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+// Run 立即检查题目，检查在后台执行
+func (r HealthCheckHandler) Run(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	if err := r.healthCheckService.Start(ctx, challengeId); err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{"ok": true})
+}
+
+// Latest 每个题目最近一次的检查结果，failed=true 时只返回未通过的
+func (r HealthCheckHandler) Latest(c echo.Context) error {
+	ctx := c.Request().Context()
+	items, err := r.healthCheckService.FindLatest(ctx, c.QueryParam("failed") == "true")
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
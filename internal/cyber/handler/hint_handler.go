@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type HintHandler struct {
+	hintService      *service.HintService
+	challengeService *service.ChallengeService
+}
+
+func NewHintHandler(hintService *service.HintService, challengeService *service.ChallengeService) *HintHandler {
+	return &HintHandler{
+		hintService:      hintService,
+		challengeService: challengeService,
+	}
+}
+
+func (r HintHandler) List(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	items, err := r.hintService.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+func (r HintHandler) Create(c echo.Context) error {
+	challengeId := c.Param("id")
+	var item models.ChallengeHint
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	if item.Content == "" || item.Cost < 0 {
+		return xe.ErrInvalidParams
+	}
+
+	ctx := c.Request().Context()
+	exists, err := r.challengeService.ExistsById(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrChallengeNotFound
+	}
+
+	item.ID = uuid.NewString()
+	item.ChallengeId = challengeId
+	if err := r.hintService.Create(ctx, &item); err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+func (r HintHandler) Update(c echo.Context) error {
+	challengeId := c.Param("id")
+	id := c.Param("hint_id")
+	var item models.ChallengeHint
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	if item.Content == "" || item.Cost < 0 {
+		return xe.ErrInvalidParams
+	}
+
+	ctx := c.Request().Context()
+	if err := r.checkOwner(ctx, challengeId, id); err != nil {
+		return err
+	}
+	return r.hintService.UpdateColumnsById(ctx, id, orz.Map{
+		"content": item.Content,
+		"cost":    item.Cost,
+		"sort":    item.Sort,
+	})
+}
+
+func (r HintHandler) Delete(c echo.Context) error {
+	id := c.Param("hint_id")
+	ctx := c.Request().Context()
+	if err := r.checkOwner(ctx, c.Param("id"), id); err != nil {
+		return err
+	}
+	return r.hintService.DeleteById(ctx, id)
+}
+
+// checkOwner 提示需要属于该题目
+func (r HintHandler) checkOwner(ctx context.Context, challengeId, id string) error {
+	hint, exists, err := r.hintService.FindByIdExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists || hint.ChallengeId != challengeId {
+		return xe.ErrHintNotFound
+	}
+	return nil
+}
+
+// Unlock 玩家解锁提示，返回提示内容
+func (r HintHandler) Unlock(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	id := c.Param("hint_id")
+	ctx := c.Request().Context()
+	accountId := identity.AccountId(c)
+
+	challenge, exists, err := r.challengeService.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
+		return xe.ErrChallengeNotFound
+	}
+	if err := r.challengeService.CheckUnlocked(ctx, accountId, challenge); err != nil {
+		return err
+	}
+
+	hint, err := r.hintService.Unlock(ctx, accountId, challengeId, id)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"id":      hint.ID,
+		"cost":    hint.Cost,
+		"content": hint.Content,
+	})
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/service"
 	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -57,10 +61,12 @@ func (r ImageHandler) Create(c echo.Context) error {
 		return err
 	}
 
-	// 创建成功后自动拉取镜像
-	go func() {
-		_ = r.imageService.Pull(context.Background(), item.ID)
-	}()
+	// 创建成功后自动拉取镜像，构建的镜像需要另外上传构建上下文
+	if !item.Built() {
+		go func() {
+			_ = r.imageService.Pull(context.Background(), item.ID)
+		}()
+	}
 
 	return orz.Ok(c, orz.Map{"ok": true})
 }
@@ -81,6 +87,9 @@ func (r ImageHandler) Update(c echo.Context) error {
 	if err := c.Bind(&item); err != nil {
 		return err
 	}
+	if err := c.Validate(&item); err != nil {
+		return err
+	}
 	item.ID = id
 
 	ctx := c.Request().Context()
@@ -154,3 +163,93 @@ func (r ImageHandler) Pull(c echo.Context) error {
 
 	return orz.Ok(c, orz.Map{"ok": true})
 }
+
+// Build 上传构建上下文构建镜像，表单字段 file，支持 tar、tar.gz 和 zip
+func (r ImageHandler) Build(c echo.Context) error {
+	id := c.Param("id")
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return xe.ErrInvalidParams
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx := c.Request().Context()
+	if err := r.imageService.BuildUpload(ctx, id, fh.Filename, f); err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{"ok": true})
+}
+
+// BuildFromPath 从服务器上的目录构建镜像，path 为相对于构建根目录的路径，为空时使用上次构建的目录
+func (r ImageHandler) BuildFromPath(c echo.Context) error {
+	id := c.Param("id")
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if err := r.imageService.BuildFromPath(ctx, id, req.Path); err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{"ok": true})
+}
+
+// BuildLog 以 SSE 推送构建日志，构建进行中时持续跟随，结束后推送 done 事件；没有进行中的构建时推送最近一次的日志
+func (r ImageHandler) BuildLog(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	item, err := r.imageService.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+		w.Flush()
+	}
+
+	log, running := r.imageService.BuildLog(id)
+	if !running {
+		if item.BuildLog != "" {
+			writeLines(strings.Split(item.BuildLog, "\n"))
+		}
+		_, _ = fmt.Fprintf(w, "event: done\ndata: %s\n\n", item.Status)
+		w.Flush()
+		return nil
+	}
+
+	var offset int
+	for {
+		lines, done, changed := log.Read(offset)
+		offset += len(lines)
+		writeLines(lines)
+		if done {
+			break
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	// 构建结束后读取最终状态
+	item, err = r.imageService.FindById(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "event: done\ndata: %s\n\n", item.Status)
+	w.Flush()
+	return nil
+}
@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/service"
 	"github.com/dushixiang/cyberpoc/internal/cyber/views"
 	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/richtext"
 	"github.com/dushixiang/cyberpoc/pkg/tools"
 	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
@@ -11,13 +18,24 @@ import (
 )
 
 func NewIndexHandler(challengeService *service.ChallengeService, instanceService *service.InstanceService,
-	solveService *service.SolveService, challengeRecordService *service.ChallengeRecordService, rankService *service.RankService) *IndexHandler {
+	solveService *service.SolveService, challengeRecordService *service.ChallengeRecordService, rankService *service.RankService,
+	attachmentService *service.AttachmentService, hintService *service.HintService, flagService *service.FlagService, tagService *service.TagService,
+	challengeFeedbackService *service.ChallengeFeedbackService, submissionLimitService *service.SubmissionLimitService,
+	submissionService *service.SubmissionService) *IndexHandler {
 	return &IndexHandler{
 		challengeService:       challengeService,
 		instanceService:        instanceService,
 		solveService:           solveService,
 		challengeRecordService: challengeRecordService,
 		rankService:            rankService,
+		attachmentService:      attachmentService,
+		hintService:            hintService,
+		flagService:            flagService,
+		tagService:             tagService,
+
+		challengeFeedbackService: challengeFeedbackService,
+		submissionLimitService:   submissionLimitService,
+		submissionService:        submissionService,
 	}
 }
 
@@ -27,71 +45,139 @@ type IndexHandler struct {
 	solveService           *service.SolveService
 	challengeRecordService *service.ChallengeRecordService
 	rankService            *service.RankService
+	attachmentService      *service.AttachmentService
+	hintService            *service.HintService
+	flagService            *service.FlagService
+	tagService             *service.TagService
+
+	challengeFeedbackService *service.ChallengeFeedbackService
+	submissionLimitService   *service.SubmissionLimitService
+	submissionService        *service.SubmissionService
+}
+
+// pageParams 分页参数，默认第1页，每页20条
+func pageParams(c echo.Context) (pageIndex, pageSize int) {
+	pageIndex, pageSize = 1, 20
+	if p, err := strconv.Atoi(c.QueryParam("pageIndex")); err == nil && p > 0 {
+		pageIndex = p
+	}
+	if p, err := strconv.Atoi(c.QueryParam("pageSize")); err == nil && p > 0 {
+		pageSize = p
+	}
+	return pageIndex, pageSize
 }
 
 func (r IndexHandler) ChallengePaging(c echo.Context) error {
 	name := c.QueryParam("name")
 	category := c.QueryParam("category")
 	difficulty := c.QueryParam("difficulty")
-	pr := orz.GetPageRequest(c)
+	tag := c.QueryParam("tag")
+	pageIndex, pageSize := pageParams(c)
+
+	// 只返回已上线且未下线的题目
+	ctx := c.Request().Context()
+	items, total, err := r.challengeService.PagingVisible(ctx, (pageIndex-1)*pageSize, pageSize, name, category, difficulty, tag, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	challenges, err := r.simples(ctx, identity.AccountId(c), items)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"items": challenges,
+		"total": total,
+	})
+}
 
-	builder := orz.NewPageBuilder(r.challengeService.Repository).
-		PageRequest(pr).
-		Equal("enabled", true).
-		Contains("name", name).
-		Contains("category", category).
-		Contains("difficulty", difficulty).
-		SortByDesc("sort", "sort")
+// SearchChallenges 按名称、标签、类别、描述搜索题目，按匹配程度排序
+func (r IndexHandler) SearchChallenges(c echo.Context) error {
+	q := c.QueryParam("q")
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
 
 	ctx := c.Request().Context()
-	page, err := builder.Execute(ctx)
+	matches, err := r.challengeService.Search(ctx, identity.AccountId(c), q, time.Now().UnixMilli(), limit)
 	if err != nil {
 		return err
 	}
+	var items = make([]models.Challenge, 0, len(matches))
+	for _, match := range matches {
+		items = append(items, match.Challenge)
+	}
+	challenges, err := r.simples(ctx, identity.AccountId(c), items)
+	if err != nil {
+		return err
+	}
+	for i := range challenges {
+		challenges[i].Score = matches[i].Score
+	}
+	return orz.Ok(c, orz.Map{
+		"items": challenges,
+		"total": len(challenges),
+	})
+}
 
-	var challengeIds = make([]string, 0, len(page.Items))
-	for _, item := range page.Items {
+// simples 转换为题目列表项，包含挑战人数、通关人数、标签和当前用户的通关及解锁状态
+func (r IndexHandler) simples(ctx context.Context, accountId string, items []models.Challenge) ([]views.ChallengeSimple, error) {
+	var challengeIds = make([]string, 0, len(items))
+	for _, item := range items {
 		challengeIds = append(challengeIds, item.ID)
 	}
 	groupCount1, err := r.challengeRecordService.GroupCountByChallengeIdIn(ctx, challengeIds)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	groupCount2, err := r.solveService.GroupCountByChallengeIdIn(ctx, challengeIds)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var userGroup = make(map[string]int64)
-	accountId := identity.AccountId(c)
 	if accountId != "" {
 		userGroup, err = r.solveService.GroupCountByChallengeIdInAndUserId(ctx, challengeIds, accountId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	progress, err := r.challengeService.Progress(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := r.tagService.NamesByChallengeIdIn(ctx, challengeIds)
+	if err != nil {
+		return nil, err
+	}
+	feedback, err := r.challengeFeedbackService.Summaries(ctx, challengeIds)
+	if err != nil {
+		return nil, err
+	}
 
-	var challenges = make([]views.ChallengeSimple, 0, len(page.Items))
-	for _, item := range page.Items {
+	var challenges = make([]views.ChallengeSimple, 0, len(items))
+	for _, item := range items {
 		challenges = append(challenges, views.ChallengeSimple{
 			ID:           item.ID,
 			Name:         item.Name,
 			Category:     item.Category,
 			Difficulty:   item.Difficulty,
-			Points:       item.Points,
+			Points:       item.CurrentPoints(groupCount2[item.ID]),
 			CreatedAt:    item.CreatedAt,
 			UpdatedAt:    item.UpdatedAt,
 			AttemptCount: groupCount1[item.ID],
 			SolvedCount:  groupCount2[item.ID],
 			Solved:       userGroup[item.ID] > 0,
+			Instance:     item.RequiresInstance(),
+			Locked:       !progress.Unlocked(item),
+			Tags:         tags[item.ID],
+			Rating:       feedback[item.ID].Rating,
+			RatingCount:  feedback[item.ID].RatingCount,
 		})
 	}
-
-	return orz.Ok(c, orz.Map{
-		"items": challenges,
-		"total": page.Total,
-	})
+	return challenges, nil
 }
 
 func (r IndexHandler) GetChallenge(c echo.Context) error {
@@ -101,7 +187,7 @@ func (r IndexHandler) GetChallenge(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	if !exists {
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
 		return xe.ErrChallengeNotFound
 	}
 
@@ -110,6 +196,14 @@ func (r IndexHandler) GetChallenge(c echo.Context) error {
 		SolvedCount:  0,
 		AttemptCount: 0,
 	}
+	view.Tags, err = r.tagService.Names(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	view.Feedback, err = r.challengeFeedbackService.Summary(ctx, challengeId)
+	if err != nil {
+		return err
+	}
 	attemptCount, err := r.challengeRecordService.CountByChallengeId(ctx, challengeId)
 	if err != nil {
 		return err
@@ -120,14 +214,52 @@ func (r IndexHandler) GetChallenge(c echo.Context) error {
 		return err
 	}
 	view.SolvedCount = solvedCount
+	view.CurrentPoints = challenge.CurrentPoints(solvedCount)
+
+	// 不向玩家暴露 Flag 和检查器（检查脚本相当于题解）
+	view.Flag = ""
+	view.CheckerImage = ""
+	view.CheckerScript = ""
+	// 保存时已清理，这里再清理一次以覆盖旧数据
+	view.Html = richtext.Sanitize(challenge.Html)
 
 	accountId := identity.AccountId(c)
+	progress, err := r.challengeService.Progress(ctx, accountId)
+	if err != nil {
+		return err
+	}
+	if !progress.Unlocked(challenge) {
+		// 未解锁的题目只展示名称和解锁条件
+		view.Locked = true
+		view.Description = ""
+		view.Html = ""
+		return orz.Ok(c, view)
+	}
+	view.DescriptionHtml = richtext.Markdown(challenge.Description)
+
+	view.Hints, err = r.hintService.Views(ctx, challengeId, accountId)
+	if err != nil {
+		return err
+	}
+	view.Stages, err = r.flagService.StageViews(ctx, challengeId, accountId)
+	if err != nil {
+		return err
+	}
 	if accountId != "" {
-		count, err := r.solveService.CountByChallengeIdAndUserId(ctx, challengeId, accountId)
-		if err != nil {
-			return err
-		}
-		view.Solved = count > 0
+		view.Solved = progress.Solved[challengeId]
+	}
+
+	attachments, err := r.attachmentService.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	view.Attachments = make([]views.AttachmentView, 0, len(attachments))
+	for _, attachment := range attachments {
+		view.Attachments = append(view.Attachments, views.AttachmentView{
+			ID:   attachment.ID,
+			Name: attachment.Name,
+			Size: attachment.Size,
+		})
 	}
 
 	return orz.Ok(c, view)
@@ -183,6 +315,28 @@ func (r IndexHandler) ChallengeRun(c echo.Context) error {
 	return r.instanceService.Run(ctx, accountId, challengeId)
 }
 
+// StartChallenge 开始挑战无需启动环境的题目，通关耗时从此时开始计算，未调用时从第一次提交开始计算
+func (r IndexHandler) StartChallenge(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	ctx := c.Request().Context()
+	accountId := identity.AccountId(c)
+
+	challenge, exists, err := r.challengeService.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
+		return xe.ErrChallengeNotFound
+	}
+	if challenge.RequiresInstance() {
+		return xe.ErrInvalidParams
+	}
+	if err := r.challengeService.CheckUnlocked(ctx, accountId, challenge); err != nil {
+		return err
+	}
+	return r.challengeRecordService.RecordStart(ctx, accountId, challenge)
+}
+
 func (r IndexHandler) DestroyInstance(c echo.Context) error {
 	challengeId := c.Param("challenge_id")
 	ctx := c.Request().Context()
@@ -205,14 +359,39 @@ func (r IndexHandler) SubmitFlag(c echo.Context) error {
 		return err
 	}
 
-	instanceId := tools.Md5Sign(accountId, challengeId)
-	ok, err := r.instanceService.SubmitFlag(ctx, instanceId, flag.Flag)
+	challenge, exists, err := r.challengeService.FindByIdExists(ctx, challengeId)
 	if err != nil {
 		return err
 	}
-	return orz.Ok(c, orz.Map{
-		"ok": ok,
-	})
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
+		return xe.ErrChallengeNotFound
+	}
+	if err := r.challengeService.CheckUnlocked(ctx, accountId, challenge); err != nil {
+		return err
+	}
+	var instanceId string
+	if challenge.RequiresInstance() {
+		instanceId = tools.Md5Sign(accountId, challengeId)
+	}
+	if err := r.submissionLimitService.Take(accountId, c.RealIP(), challenge); err != nil {
+		var limited *xe.RateLimitError
+		if errors.As(err, &limited) {
+			r.submissionService.Record(ctx, accountId, challengeId, instanceId, flag.Flag, c.RealIP(), nil)
+		}
+		return err
+	}
+
+	var result *views.FlagResult
+	if challenge.RequiresInstance() {
+		result, err = r.instanceService.SubmitFlag(ctx, instanceId, flag.Flag)
+	} else {
+		result, err = r.instanceService.SubmitStaticFlag(ctx, accountId, challenge, flag.Flag)
+	}
+	if err != nil {
+		return err
+	}
+	r.submissionService.Record(ctx, accountId, challengeId, instanceId, flag.Flag, c.RealIP(), result)
+	return orz.Ok(c, result)
 }
 
 func (r IndexHandler) GetRanks(c echo.Context) error {
@@ -230,3 +409,35 @@ func (r IndexHandler) GetRanks(c echo.Context) error {
 		"updated_at": ts,
 	})
 }
+
+// GetUserSolves 用户主页：通关记录及奖励
+func (r IndexHandler) GetUserSolves(c echo.Context) error {
+	userId := c.Param("user_id")
+	ctx := c.Request().Context()
+	user, exists, err := r.solveService.FindUserView(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrAccountNotFound
+	}
+	solves, err := r.solveService.FindByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	var points, bonus, firstBloods int64
+	for _, solve := range solves {
+		points += solve.Points
+		bonus += solve.Bonus
+		if solve.SolveOrder == 1 {
+			firstBloods++
+		}
+	}
+	return orz.Ok(c, orz.Map{
+		"user":         user,
+		"items":        solves,
+		"points":       points,
+		"bonus":        bonus,
+		"first_bloods": firstBloods,
+	})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+// exportBatchSize 导出时每次查询的记录数
+const exportBatchSize = 1000
+
+type SubmissionHandler struct {
+	submissionService *service.SubmissionService
+}
+
+func NewSubmissionHandler(submissionService *service.SubmissionService) *SubmissionHandler {
+	return &SubmissionHandler{
+		submissionService: submissionService,
+	}
+}
+
+// submissionFilter 查询参数 challengeId、userId、ip、correct(true/false)、start、end(毫秒)
+func submissionFilter(c echo.Context) repo.SubmissionFilter {
+	filter := repo.SubmissionFilter{
+		ChallengeId: c.QueryParam("challengeId"),
+		UserId:      c.QueryParam("userId"),
+		IP:          c.QueryParam("ip"),
+	}
+	if correct, err := strconv.ParseBool(c.QueryParam("correct")); err == nil {
+		filter.Correct = &correct
+	}
+	filter.Start, _ = strconv.ParseInt(c.QueryParam("start"), 10, 64)
+	filter.End, _ = strconv.ParseInt(c.QueryParam("end"), 10, 64)
+	return filter
+}
+
+// Paging 分页查询提交记录
+func (r SubmissionHandler) Paging(c echo.Context) error {
+	pageIndex, pageSize := pageParams(c)
+	ctx := c.Request().Context()
+	items, total, err := r.submissionService.Paging(ctx, (pageIndex-1)*pageSize, pageSize, submissionFilter(c))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"items": items,
+		"total": total,
+	})
+}
+
+// Export 按查询条件导出提交记录（csv）
+func (r SubmissionHandler) Export(c echo.Context) error {
+	filter := submissionFilter(c)
+	// 固定截止时间，避免导出过程中的新提交导致分页错位
+	if filter.End == 0 {
+		filter.End = time.Now().UnixMilli() + 1
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	w.Header().Set(echo.HeaderContentDisposition, `attachment; filename="submissions.csv"`)
+	w.WriteHeader(http.StatusOK)
+	// 带 BOM，Excel 打开时不乱码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "user_id", "user_name", "challenge_id", "challenge_name", "instance_id", "flag", "correct", "stage", "limited", "ip"}); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	for offset := 0; ; offset += exportBatchSize {
+		items, err := r.submissionService.Find(ctx, offset, exportBatchSize, filter)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			record := []string{
+				time.UnixMilli(item.CreatedAt).Format(time.RFC3339),
+				item.UserId,
+				item.UserName,
+				item.ChallengeId,
+				item.ChallengeName,
+				item.InstanceId,
+				item.Flag,
+				strconv.FormatBool(item.Correct),
+				item.Stage,
+				strconv.FormatBool(item.Limited),
+				item.IP,
+			}
+			for i := range record {
+				record[i] = csvSafe(record[i])
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		if len(items) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// csvSafe 玩家可控的内容（包括 X-Forwarded-For 中的 IP）可能以 = + - @ 开头，
+// 加上单引号防止被表格软件当作公式执行，开头的空白字符不影响判断
+func csvSafe(s string) string {
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	if trimmed != s || (trimmed != "" && strings.ContainsRune("=+-@", rune(trimmed[0]))) {
+		return "'" + s
+	}
+	return s
+}
+
+// Stats 题目的提交统计及最常见的错误答案
+func (r SubmissionHandler) Stats(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	stats, err := r.submissionService.Stats(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, stats)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type TagHandler struct {
+	tagService       *service.TagService
+	challengeService *service.ChallengeService
+}
+
+func NewTagHandler(tagService *service.TagService, challengeService *service.ChallengeService) *TagHandler {
+	return &TagHandler{
+		tagService:       tagService,
+		challengeService: challengeService,
+	}
+}
+
+// List 全部标签及关联的题目数量
+func (r TagHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	items, err := r.tagService.FindAllWithCount(ctx)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+func (r TagHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	return r.tagService.Delete(ctx, id)
+}
+
+// GetChallengeTags 题目的标签
+func (r TagHandler) GetChallengeTags(c echo.Context) error {
+	challengeId := c.Param("id")
+	ctx := c.Request().Context()
+	names, err := r.tagService.Names(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, names)
+}
+
+// SetChallengeTags 覆盖题目的标签，接收 {tags: []}
+func (r TagHandler) SetChallengeTags(c echo.Context) error {
+	challengeId := c.Param("id")
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	exists, err := r.challengeService.ExistsById(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrChallengeNotFound
+	}
+	return r.tagService.SetTags(ctx, challengeId, req.Tags)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/service"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+type WriteupHandler struct {
+	writeupService *service.WriteupService
+}
+
+func NewWriteupHandler(writeupService *service.WriteupService) *WriteupHandler {
+	return &WriteupHandler{
+		writeupService: writeupService,
+	}
+}
+
+// List 题目下审核通过的题解，只有通关的玩家可以查看
+func (r WriteupHandler) List(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	ctx := c.Request().Context()
+	items, err := r.writeupService.Approved(ctx, challengeId, identity.AccountId(c))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, items)
+}
+
+// Mine 自己提交的题解及审核状态
+func (r WriteupHandler) Mine(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	ctx := c.Request().Context()
+	item, err := r.writeupService.Mine(ctx, challengeId, identity.AccountId(c))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+// Submit 提交或修改自己的题解
+func (r WriteupHandler) Submit(c echo.Context) error {
+	challengeId := c.Param("challenge_id")
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	item, err := r.writeupService.Submit(ctx, challengeId, identity.AccountId(c), req.Content)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, item)
+}
+
+// Paging 管理端分页查询题解，可按状态和题目过滤
+func (r WriteupHandler) Paging(c echo.Context) error {
+	pageIndex, pageSize := pageParams(c)
+	ctx := c.Request().Context()
+	items, total, err := r.writeupService.Paging(ctx, (pageIndex-1)*pageSize, pageSize, c.QueryParam("status"), c.QueryParam("challengeId"))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"items": items,
+		"total": total,
+	})
+}
+
+// Approve 审核通过
+func (r WriteupHandler) Approve(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	return r.writeupService.Review(ctx, id, identity.AccountId(c), models.WriteupStatusApproved, "")
+}
+
+// Reject 驳回，可以附带原因
+func (r WriteupHandler) Reject(c echo.Context) error {
+	id := c.Param("id")
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	return r.writeupService.Review(ctx, id, identity.AccountId(c), models.WriteupStatusRejected, req.Reason)
+}
+
+func (r WriteupHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	return r.writeupService.DeleteById(ctx, id)
+}
@@ -0,0 +1,15 @@
+package models
+
+// Announcement 公告，题目定时上线时自动发布
+type Announcement struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	Title       string `json:"title"`                                  // 标题
+	Content     string `json:"content"`                                // 内容
+	ChallengeId string `gorm:"index" json:"challenge_id"`              // 上线的题目ID，手动发布时为空
+	ReleaseAt   int64  `json:"release_at"`                             // 题目的上线时间，同一次上线只发布一次
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 发布时间
+}
+
+func (m Announcement) TableName() string {
+	return "announcements"
+}
@@ -0,0 +1,15 @@
+package models
+
+// Category 题目类别，Challenge.Category 保存类别名称
+type Category struct {
+	ID        string `gorm:"primary_key;size:36" json:"id"`
+	Name      string `gorm:"uniqueIndex;size:64" json:"name"`        // 名称
+	Icon      string `json:"icon"`                                   // 图标
+	Sort      int64  `json:"sort" gorm:"index"`                      // 排序，值越小越靠前
+	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+	UpdatedAt int64  `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m Category) TableName() string {
+	return "categories"
+}
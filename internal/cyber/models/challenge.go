@@ -1,19 +1,49 @@
 package models
 
+import (
+	"math"
+
+	"gorm.io/datatypes"
+)
+
 // Challenge 题目
 type Challenge struct {
 	ID          string `gorm:"primary_key" json:"id"`
-	Name        string `json:"name"`         // 题目名称 (e.g., "Easy SQL Injection")
-	Description string `json:"description"`  // 题目描述，包括背景、提示等
-	Category    string `json:"category"`     // 题目类别
-	Difficulty  string `json:"difficulty"`   // 难度等级 (easy、medium、hard)
-	Points      int64  `json:"points"`       // 题目分值
-	Flag        string `json:"flag"`         // 题目的静态Flag (用于无需启动容器的题目)
-	DynamicFlag bool   `json:"dynamic_flag"` // 是否动态Flag
-	Enabled     bool   `json:"enabled"`      // 是否启用
-	ImageId     string `json:"image_id"`     // 镜像ID
-	Duration    int    `json:"duration"`     // 持续时长 单位：分钟
-	Html        string `json:"html"`         // HTML内容
+	Slug        string `json:"slug" gorm:"uniqueIndex:uni_challenges_slug"` // 唯一标识，用于题目包导入导出
+	Name        string `json:"name"`                                        // 题目名称 (e.g., "Easy SQL Injection")
+	Description string `json:"description"`                                 // 题目描述，包括背景、提示等
+	Category    string `json:"category"`                                    // 题目类别
+	Difficulty  string `json:"difficulty"`                                  // 难度等级 (easy、medium、hard)
+	Points      int64  `json:"points"`                                      // 题目分值
+	Flag        string `json:"flag"`                                        // 题目的静态Flag (用于无需启动容器的题目)
+	DynamicFlag bool   `json:"dynamic_flag"`                                // 是否动态Flag
+	Enabled     bool   `json:"enabled"`                                     // 是否启用
+	ImageId     string `json:"image_id"`                                    // 镜像ID
+	Duration    int    `json:"duration"`                                    // 持续时长 单位：分钟
+	Html        string `json:"html"`                                        // HTML内容
+
+	FlagMatch        string `json:"flag_match"`         // Flag 匹配方式 exact、case-insensitive、regex、normalized，为空时忽略大小写
+	FlagStripWrapper bool   `json:"flag_strip_wrapper"` // 比较前去掉 flag{} 等包裹
+
+	ScoreType string `json:"score_type"` // 计分方式 static、dynamic，为空时按 static 处理
+	Minimum   int64  `json:"minimum"`    // dynamic: 最低分值
+	Decay     int64  `json:"decay"`      // dynamic: 第 decay+1 位解题者起降到最低分值
+
+	BonusType string                     `json:"bonus_type"` // 前 N 名解题奖励方式 points、percent，为空时不奖励
+	Bonuses   datatypes.JSONSlice[int64] `json:"bonuses"`    // 第 i 名的奖励，points 为分数，percent 为初始分值的百分比
+
+	Prerequisites       datatypes.JSONSlice[string] `json:"prerequisites"`         // 前置题目ID，全部通关后才解锁
+	UnlockScore         int64                       `json:"unlock_score"`          // 解锁所需的最低得分
+	UnlockCategory      string                      `json:"unlock_category"`       // 解锁需要通关的题目类别
+	UnlockCategoryCount int64                       `json:"unlock_category_count"` // 解锁需要通关的该类别题目数量
+
+	ReleaseAt int64 `json:"release_at" gorm:"index"` // 定时上线时间，为0时不限制
+	HideAt    int64 `json:"hide_at"`                 // 定时下线时间，为0时不限制
+
+	CheckerImage  string `json:"checker_image"`                   // 健康检查器镜像，为空时使用默认镜像执行检查脚本
+	CheckerScript string `gorm:"type:text" json:"checker_script"` // 健康检查脚本，在检查器镜像中以 sh -c 执行
+
+	SubmitLimit int `json:"submit_limit"` // 每个用户在窗口期内的提交次数上限，0 时使用全局配置，小于 0 时不限制
 
 	Sort int64 `json:"sort" gorm:"index"` // 排序，值越大越靠前
 
@@ -24,3 +54,96 @@ type Challenge struct {
 func (m Challenge) TableName() string {
 	return "challenges"
 }
+
+// 难度等级
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Difficulties 全部难度等级，按从易到难排列
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// RequiresInstance 是否需要启动环境，未配置镜像的题目（如密码学、取证）直接提交 Flag
+func (m Challenge) RequiresInstance() bool {
+	return m.ImageId != ""
+}
+
+// Visible 在 now（毫秒）时玩家是否可见，需要启用且处于上线时间和下线时间之间
+func (m Challenge) Visible(now int64) bool {
+	if !m.Enabled {
+		return false
+	}
+	if m.ReleaseAt > 0 && now < m.ReleaseAt {
+		return false
+	}
+	return m.HideAt == 0 || now < m.HideAt
+}
+
+// Conditional 是否配置了解锁条件
+func (m Challenge) Conditional() bool {
+	return len(m.Prerequisites) > 0 || m.UnlockScore > 0 || m.UnlockCategoryCount > 0
+}
+
+const (
+	ScoreTypeStatic  = "static"
+	ScoreTypeDynamic = "dynamic"
+)
+
+// Dynamic 是否按解题人数动态计分
+func (m Challenge) Dynamic() bool {
+	return m.ScoreType == ScoreTypeDynamic && m.Decay > 0
+}
+
+// CurrentPoints 当前分值，动态计分时以 Points 为初始分值，随解题人数按二次曲线衰减到 Minimum：
+//
+//	value = (Minimum - Points) / Decay² × (solves - 1)² + Points
+func (m Challenge) CurrentPoints(solves int64) int64 {
+	if !m.Dynamic() {
+		return m.Points
+	}
+	if solves > 0 {
+		solves--
+	}
+	value := float64(m.Minimum-m.Points)/float64(m.Decay*m.Decay)*float64(solves*solves) + float64(m.Points)
+	points := int64(math.Ceil(value))
+	if points < m.Minimum {
+		return m.Minimum
+	}
+	return points
+}
+
+// SolvePoints 第 solves 个解题者通关记录的分值，配置了阶段的题目由阶段分数组成总分，通关记录不再计分
+func (m Challenge) SolvePoints(solves int64, staged bool) int64 {
+	if staged {
+		return 0
+	}
+	return m.CurrentPoints(solves)
+}
+
+const (
+	BonusTypePoints  = "points"
+	BonusTypePercent = "percent"
+)
+
+// Bonus 第 order 个解题者（从 1 开始）的奖励分数，百分比按初始分值计算，避免随动态计分变化
+func (m Challenge) Bonus(order int64) int64 {
+	if order <= 0 || order > int64(len(m.Bonuses)) {
+		return 0
+	}
+	bonus := m.Bonuses[order-1]
+	switch m.BonusType {
+	case BonusTypePoints:
+		return bonus
+	case BonusTypePercent:
+		return int64(math.Ceil(float64(m.Points) * float64(bonus) / 100))
+	default:
+		return 0
+	}
+}
+
+// HasChecker 是否配置了健康检查器
+func (m Challenge) HasChecker() bool {
+	return m.CheckerImage != "" || m.CheckerScript != ""
+}
@@ -0,0 +1,19 @@
+package models
+
+// ChallengeAttachment 题目附件
+type ChallengeAttachment struct {
+	ID            string `gorm:"primary_key" json:"id"`
+	ChallengeId   string `json:"challenge_id" gorm:"index"` // 题目ID
+	Name          string `json:"name"`                      // 文件名
+	Size          int64  `json:"size"`                      // 文件大小（字节）
+	Sha256        string `json:"sha256"`                    // 文件摘要
+	StorageKey    string `json:"-"`                         // 存储后端中的路径
+	DownloadCount int64  `json:"download_count"`            // 下载次数
+
+	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+	UpdatedAt int64 `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m ChallengeAttachment) TableName() string {
+	return "challenge_attachments"
+}
@@ -0,0 +1,17 @@
+package models
+
+// ChallengeFeedback 玩家对题目的评价，每人每题一条
+type ChallengeFeedback struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	ChallengeId string `gorm:"uniqueIndex:idx_feedback_challenge_user;size:36" json:"challenge_id"`
+	UserId      string `gorm:"uniqueIndex:idx_feedback_challenge_user;size:36" json:"user_id"`
+	Rating      int    `json:"rating"`                                 // 评分 1-5
+	Difficulty  string `json:"difficulty"`                             // 体感难度 (easy、medium、hard)，可不填
+	Comment     string `gorm:"type:text" json:"comment"`               // 评论，可不填
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+	UpdatedAt   int64  `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m ChallengeFeedback) TableName() string {
+	return "challenge_feedbacks"
+}
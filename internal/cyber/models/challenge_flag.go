@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ChallengeFlag 题目的分阶段 Flag，题目未配置时使用 Challenge.Flag / DynamicFlag
+type ChallengeFlag struct {
+	ID          string `gorm:"primary_key" json:"id"`
+	ChallengeId string `json:"challenge_id" gorm:"index"` // 题目ID
+	Name        string `json:"name"`                      // 阶段名称，如 user、root
+	Flag        string `json:"flag"`                      // 静态Flag
+	Dynamic     bool   `json:"dynamic"`                   // 是否为每个环境生成动态Flag
+	Env         string `json:"env"`                       // 动态Flag注入容器的环境变量名，默认 FLAG_<NAME>
+	Points      int64  `json:"points"`                    // 提交该阶段获得的分数
+	Optional    bool   `json:"optional"`                  // 可选阶段，不影响题目是否完成
+	Sort        int64  `json:"sort"`                      // 排序，值越小越靠前
+
+	Match        string `json:"match"`         // 匹配方式 exact、case-insensitive、regex、normalized，为空时忽略大小写
+	StripWrapper bool   `json:"strip_wrapper"` // 比较前去掉 flag{} 等包裹
+
+	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+	UpdatedAt int64 `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m ChallengeFlag) TableName() string {
+	return "challenge_flags"
+}
+
+// FlagCapture 用户提交正确的阶段 Flag 记录
+type FlagCapture struct {
+	ID          string `gorm:"primary_key" json:"id"`
+	UserId      string `json:"user_id" gorm:"uniqueIndex:idx_flag_capture_user_flag"` // 用户ID
+	FlagId      string `json:"flag_id" gorm:"uniqueIndex:idx_flag_capture_user_flag"` // 阶段ID
+	ChallengeId string `json:"challenge_id" gorm:"index"`                             // 题目ID
+	Points      int64  `json:"points"`                                                // 获得的分数
+	CapturedAt  int64  `json:"captured_at"`                                           // 提交时间
+}
+
+func (m FlagCapture) TableName() string {
+	return "flag_captures"
+}
+
+// FlagValues 环境中各阶段的动态Flag，以 JSON 存储
+type FlagValues map[string]string
+
+func (m FlagValues) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+func (m *FlagValues) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported flag values type: %T", value)
+	}
+	return json.Unmarshal(data, m)
+}
+
+func (FlagValues) GormDataType() string {
+	return "text"
+}
@@ -0,0 +1,32 @@
+package models
+
+// ChallengeHint 题目提示
+type ChallengeHint struct {
+	ID          string `gorm:"primary_key" json:"id"`
+	ChallengeId string `json:"challenge_id" gorm:"index"` // 题目ID
+	Content     string `json:"content"`                   // 提示内容
+	Cost        int64  `json:"cost"`                      // 解锁扣除的分数，0 表示免费
+	Sort        int64  `json:"sort"`                      // 排序，值越小越靠前
+
+	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+	UpdatedAt int64 `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m ChallengeHint) TableName() string {
+	return "challenge_hints"
+}
+
+// HintUnlock 用户解锁提示的记录
+type HintUnlock struct {
+	ID          string `gorm:"primary_key" json:"id"`
+	UserId      string `json:"user_id" gorm:"uniqueIndex:idx_hint_unlock_user_hint"` // 用户ID
+	HintId      string `json:"hint_id" gorm:"uniqueIndex:idx_hint_unlock_user_hint"` // 提示ID
+	ChallengeId string `json:"challenge_id" gorm:"index"`                            // 题目ID
+	Cost        int64  `json:"cost"`                                                 // 解锁时扣除的分数，提示修改后不变
+
+	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime:milli"` // 解锁时间
+}
+
+func (m HintUnlock) TableName() string {
+	return "hint_unlocks"
+}
@@ -8,6 +8,7 @@ type ChallengeRecord struct {
 	ChallengeId   string `gorm:"index" json:"challenge_id"`              // 题目ID
 	ChallengeName string `json:"challenge_name"`                         // 题目名称
 	InstanceId    string `gorm:"index" json:"instance_id"`               // 实例ID
+	Subdomain     string `gorm:"index" json:"subdomain"`                 // 子域名
 	CreatedAt     int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
 }
 
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"github.com/dushixiang/cyberpoc/pkg/tools"
+	"gorm.io/datatypes"
+)
+
+// 修订的来源
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionImport  = "import"
+	RevisionActionRestore = "restore"
+	RevisionActionSync    = "sync"
+)
+
+// ChallengeRevision 题目修订记录，每次保存题目时记录保存后的完整快照和字段变更
+type ChallengeRevision struct {
+	ID          string                                 `gorm:"primary_key;size:36" json:"id"`
+	ChallengeId string                                 `gorm:"index" json:"challenge_id"`              // 题目ID
+	Version     int64                                  `json:"version"`                                // 版本号，从1开始递增
+	Action      string                                 `json:"action"`                                 // create、update、import、restore
+	AuthorId    string                                 `json:"author_id"`                              // 修改人ID，命令行导入时为空
+	AuthorName  string                                 `json:"author_name"`                            // 修改人名称
+	Changes     datatypes.JSONSlice[tools.FieldChange] `json:"changes"`                                // 与上一次保存相比的字段变更
+	Snapshot    datatypes.JSONType[Challenge]          `json:"snapshot"`                               // 保存后的题目
+	CreatedAt   int64                                  `json:"created_at" gorm:"autoCreateTime:milli"` // 保存时间
+}
+
+func (m ChallengeRevision) TableName() string {
+	return "challenge_revisions"
+}
@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestCurrentPoints(t *testing.T) {
+	challenge := Challenge{Points: 500, ScoreType: ScoreTypeDynamic, Minimum: 100, Decay: 10}
+	cases := map[int64]int64{
+		0:   500,
+		1:   500,
+		2:   496,
+		6:   400,
+		11:  100,
+		100: 100,
+	}
+	for solves, want := range cases {
+		if got := challenge.CurrentPoints(solves); got != want {
+			t.Errorf("CurrentPoints(%d) = %d, want %d", solves, got, want)
+		}
+	}
+
+	challenge.ScoreType = ScoreTypeStatic
+	if got := challenge.CurrentPoints(100); got != 500 {
+		t.Errorf("static CurrentPoints = %d, want 500", got)
+	}
+}
+
+func TestSolvePoints(t *testing.T) {
+	challenge := Challenge{Points: 500, ScoreType: ScoreTypeDynamic, Minimum: 100, Decay: 10}
+	if got := challenge.SolvePoints(2, false); got != 496 {
+		t.Errorf("SolvePoints = %d, want 496", got)
+	}
+	if got := challenge.SolvePoints(2, true); got != 0 {
+		t.Errorf("staged SolvePoints = %d, want 0", got)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	challenge := Challenge{Enabled: true, ReleaseAt: 1000, HideAt: 2000}
+	cases := map[int64]bool{
+		999:  false,
+		1000: true,
+		1999: true,
+		2000: false,
+	}
+	for now, want := range cases {
+		if got := challenge.Visible(now); got != want {
+			t.Errorf("Visible(%d) = %v, want %v", now, got, want)
+		}
+	}
+
+	challenge.Enabled = false
+	if challenge.Visible(1500) {
+		t.Error("disabled challenge should not be visible")
+	}
+}
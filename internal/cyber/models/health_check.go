@@ -0,0 +1,17 @@
+package models
+
+// HealthCheck 题目的一次健康检查：启动临时环境，运行检查器，退出码为0时通过
+type HealthCheck struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	ChallengeId string `gorm:"index" json:"challenge_id"`              // 题目ID
+	Passed      bool   `json:"passed"`                                 // 是否通过
+	ExitCode    int    `json:"exit_code"`                              // 检查器的退出码
+	Output      string `gorm:"type:text" json:"output"`                // 检查器的输出，只保留末尾部分
+	Error       string `json:"error"`                                  // 环境或检查器未能运行时的错误
+	Duration    int64  `json:"duration"`                               // 耗时，单位：毫秒
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 检查时间
+}
+
+func (m HealthCheck) TableName() string {
+	return "health_checks"
+}
@@ -10,18 +10,33 @@ const (
 	ImageStatusFailed    ImageStatus = "failed"    // 拉取失败
 	ImageStatusVerifying ImageStatus = "verifying" // 校验中
 	ImageStatusDeleting  ImageStatus = "deleting"  // 删除中
+
+	ImageStatusBuilding    ImageStatus = "building"     // 构建中
+	ImageStatusBuildFailed ImageStatus = "build-failed" // 构建失败
+)
+
+const (
+	ImageSourcePull  = "pull"  // 从镜像仓库拉取
+	ImageSourceBuild = "build" // 从构建上下文构建
 )
 
 // Image 镜像
 type Image struct {
 	ID          string      `gorm:"primary_key" json:"id"`
-	Name        string      `json:"name"`         // 名称
-	Description string      `json:"description"`  // 详情
-	Registry    string      `json:"registry"`     // 镜像仓库地址
-	CpuLimit    float64     `json:"cpu_limit"`    // CPU限制
-	MemoryLimit int64       `json:"memory_limit"` // 内存限制(MB)
-	Exposed     string      `json:"exposed"`      // 暴露端口
-	Status      ImageStatus `json:"status"`       // 状态
+	Name        string      `json:"name"`                                           // 名称
+	Description string      `json:"description"`                                    // 详情
+	Registry    string      `json:"registry"`                                       // 镜像仓库地址
+	CpuLimit    float64     `json:"cpu_limit"`                                      // CPU限制
+	MemoryLimit int64       `json:"memory_limit"`                                   // 内存限制(MB)
+	Exposed     string      `json:"exposed"`                                        // 暴露端口
+	Protocol    string      `json:"protocol" validate:"omitempty,oneof=http https"` // 网关转发到容器使用的协议 http、https，为空时为 http
+	Status      ImageStatus `json:"status"`                                         // 状态
+
+	Source     string `json:"source"`             // 来源 pull、build，为空时从镜像仓库拉取
+	Dockerfile string `json:"dockerfile"`         // build: Dockerfile 相对于构建上下文的路径，默认 Dockerfile
+	BuildPath  string `json:"build_path"`         // build: 相对于构建根目录的构建上下文，上传构建时为空
+	BuildLog   string `gorm:"type:text" json:"-"` // build: 最近一次构建的日志
+	BuiltAt    int64  `json:"built_at"`           // build: 最近一次构建成功的时间
 
 	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
 	UpdatedAt int64 `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
@@ -30,3 +45,8 @@ type Image struct {
 func (m Image) TableName() string {
 	return "images"
 }
+
+// Built 是否从构建上下文构建，Registry 只作为本地镜像名
+func (m Image) Built() bool {
+	return m.Source == ImageSourceBuild
+}
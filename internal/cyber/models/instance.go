@@ -18,13 +18,16 @@ type Instance struct {
 	ChallengeId   string         `gorm:"index" json:"challenge_id"`   // 题目ID
 	ChallengeName string         `json:"challenge_name"`              // 挑战名称
 	Flag          string         `json:"flag"`                        // Flag
+	StageFlags    FlagValues     `json:"stage_flags"`                 // 各阶段的动态Flag，key 为 ChallengeFlag.ID
 	Exposed       string         `json:"exposed"`                     // 暴露端口
+	Protocol      string         `json:"protocol"`                    // 网关转发使用的协议，取自镜像
 	Duration      int            `json:"duration"`                    // 持续时长 单位：分钟
 	CpuLimit      float64        `json:"cpu_limit"`                   // CPU限制
 	MemoryLimit   int64          `json:"memory_limit"`                // 内存限制(MB)
 	Status        InstanceStatus `gorm:"index;size:20" json:"status"` // 状态
-	Subdomain     string         `json:"subdomain"`                   // 子域名
+	Subdomain     string         `gorm:"index" json:"subdomain"`      // 子域名
 	AccessUrl     string         `json:"access_url"`                  // 访问地址
+	Target        string         `json:"target"`                      // 网关转发地址
 	Message       string         `json:"message"`                     // 消息
 	CreatedAt     int64          `json:"created_at"`                  // 创建时间
 	ExpiresAt     int64          `json:"expires_at"`                  // 失效时间
@@ -33,3 +36,11 @@ type Instance struct {
 func (m Instance) TableName() string {
 	return "instances"
 }
+
+// UpstreamProtocol 网关转发到容器使用的协议，为空时为 http
+func (m Instance) UpstreamProtocol() string {
+	if m.Protocol == "" {
+		return "http"
+	}
+	return m.Protocol
+}
@@ -7,6 +7,7 @@ type Rank struct {
 	Score        int64  `json:"score"`                // 总积分
 	TotalTime    int64  `json:"total_time"`           // 总耗时（秒）
 	TotalTimeStr string `json:"total_time_str"`       // 总耗时字符串
+	Partial      bool   `json:"partial"`              // 没有通关记录，只完成了部分阶段，同分时排在通关的用户之后
 	UpdatedAt    int64  `json:"updated_at" gorm:"autoUpdateTime:milli"`
 }
 
@@ -6,6 +6,8 @@ type Solve struct {
 	UserId      string `gorm:"index" json:"user_id"`      // 用户ID
 	ChallengeId string `gorm:"index" json:"challenge_id"` // 题目ID
 	Points      int64  `json:"points"`                    // 题目分值
+	Bonus       int64  `json:"bonus"`                     // 前 N 名解题奖励
+	SolveOrder  int64  `json:"solve_order"`               // 第几个解出该题
 	StartAt     int64  `json:"start_at"`                  // 开始时间
 	SolvedAt    int64  `json:"solved_at"`                 // 通过时间
 	UsedTime    int64  `json:"used_time"`                 // 使用时长
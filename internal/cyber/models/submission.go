@@ -0,0 +1,19 @@
+package models
+
+// Submission 一次 Flag 提交，错误的提交也会记录
+type Submission struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	UserId      string `gorm:"index;size:36" json:"user_id"`
+	ChallengeId string `gorm:"index;size:36" json:"challenge_id"`
+	InstanceId  string `json:"instance_id"`                                  // 环境ID，无需启动环境的题目为空
+	Flag        string `gorm:"type:text" json:"flag"`                        // 提交的内容，过长时截断
+	Correct     bool   `json:"correct"`                                      // 是否正确
+	Stage       string `json:"stage"`                                        // 命中的阶段
+	Limited     bool   `json:"limited"`                                      // 提交过于频繁被拒绝，未校验 Flag
+	IP          string `json:"ip"`                                           // 提交者的IP
+	CreatedAt   int64  `json:"created_at" gorm:"index;autoCreateTime:milli"` // 提交时间
+}
+
+func (m Submission) TableName() string {
+	return "submissions"
+}
@@ -0,0 +1,23 @@
+package models
+
+// Tag 题目标签
+type Tag struct {
+	ID        string `gorm:"primary_key;size:36" json:"id"`
+	Name      string `gorm:"uniqueIndex;size:64" json:"name"`        // 名称
+	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 创建时间
+}
+
+func (m Tag) TableName() string {
+	return "tags"
+}
+
+// ChallengeTag 题目与标签的关联
+type ChallengeTag struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	ChallengeId string `json:"challenge_id" gorm:"uniqueIndex:idx_challenge_tag"` // 题目ID
+	TagId       string `json:"tag_id" gorm:"uniqueIndex:idx_challenge_tag;index"` // 标签ID
+}
+
+func (m ChallengeTag) TableName() string {
+	return "challenge_tags"
+}
@@ -0,0 +1,26 @@
+package models
+
+// 题解审核状态
+const (
+	WriteupStatusPending  = "pending"
+	WriteupStatusApproved = "approved"
+	WriteupStatusRejected = "rejected"
+)
+
+// Writeup 玩家通关后提交的题解，审核通过后对同样通关的玩家可见
+type Writeup struct {
+	ID          string `gorm:"primary_key;size:36" json:"id"`
+	ChallengeId string `gorm:"uniqueIndex:idx_writeup_challenge_user;size:36" json:"challenge_id"`
+	UserId      string `gorm:"uniqueIndex:idx_writeup_challenge_user;size:36" json:"user_id"`
+	Content     string `gorm:"type:text" json:"content"`               // markdown 内容
+	Status      string `gorm:"index;size:16" json:"status"`            // 审核状态
+	Reason      string `json:"reason"`                                 // 驳回原因
+	ReviewerId  string `json:"reviewer_id"`                            // 审核人
+	ReviewedAt  int64  `json:"reviewed_at"`                            // 审核时间
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime:milli"` // 提交时间
+	UpdatedAt   int64  `json:"updated_at" gorm:"autoUpdateTime:milli"` // 更新时间
+}
+
+func (m Writeup) TableName() string {
+	return "writeups"
+}
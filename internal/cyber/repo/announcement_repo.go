@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type AnnouncementRepo struct {
+	orz.Repository[models.Announcement, string]
+}
+
+func NewAnnouncementRepo(db *gorm.DB) *AnnouncementRepo {
+	return &AnnouncementRepo{
+		Repository: orz.NewRepository[models.Announcement, string](db),
+	}
+}
+
+func (r AnnouncementRepo) ExistsByChallengeIdAndReleaseAt(ctx context.Context, challengeId string, releaseAt int64) (bool, error) {
+	var count int64
+	err := r.GetDB(ctx).
+		Model(&models.Announcement{}).
+		Where("challenge_id = ? and release_at = ?", challengeId, releaseAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindLatest 最新的公告
+func (r AnnouncementRepo) FindLatest(ctx context.Context, limit int) (items []models.Announcement, err error) {
+	err = r.GetDB(ctx).Order("created_at desc").Limit(limit).Find(&items).Error
+	return
+}
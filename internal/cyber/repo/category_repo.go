@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type CategoryRepo struct {
+	orz.Repository[models.Category, string]
+}
+
+func NewCategoryRepo(db *gorm.DB) *CategoryRepo {
+	return &CategoryRepo{
+		Repository: orz.NewRepository[models.Category, string](db),
+	}
+}
+
+func (r CategoryRepo) FindByName(ctx context.Context, name string) (item models.Category, exists bool, err error) {
+	var items []models.Category
+	err = r.GetDB(ctx).Where("name = ?", name).Limit(1).Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
+// FindAllSorted 全部类别，按 sort 升序
+func (r CategoryRepo) FindAllSorted(ctx context.Context) (items []models.Category, err error) {
+	err = r.GetDB(ctx).Order("sort asc, created_at asc").Find(&items).Error
+	return
+}
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+func NewChallengeAttachmentRepo(db *gorm.DB) *ChallengeAttachmentRepo {
+	return &ChallengeAttachmentRepo{
+		Repository: orz.NewRepository[models.ChallengeAttachment, string](db),
+	}
+}
+
+type ChallengeAttachmentRepo struct {
+	orz.Repository[models.ChallengeAttachment, string]
+}
+
+func (r ChallengeAttachmentRepo) FindByChallengeId(ctx context.Context, challengeId string) (items []models.ChallengeAttachment, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ?", challengeId).Order("name asc").Find(&items).Error
+	return
+}
+
+func (r ChallengeAttachmentRepo) FindByChallengeIdAndName(ctx context.Context, challengeId, name string) (items []models.ChallengeAttachment, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ? and name = ?", challengeId, name).Find(&items).Error
+	return
+}
+
+func (r ChallengeAttachmentRepo) IncrDownloadCount(ctx context.Context, id string) error {
+	return r.GetDB(ctx).Model(&models.ChallengeAttachment{}).
+		Where("id = ?", id).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error
+}
+
+// SumDownloadCountByChallengeId 题目全部附件的下载次数
+func (r ChallengeAttachmentRepo) SumDownloadCountByChallengeId(ctx context.Context, challengeId string) (total int64, err error) {
+	err = r.GetDB(ctx).Model(&models.ChallengeAttachment{}).
+		Select("coalesce(sum(download_count), 0)").
+		Where("challenge_id = ?", challengeId).
+		Scan(&total).Error
+	return
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type ChallengeFeedbackRepo struct {
+	orz.Repository[models.ChallengeFeedback, string]
+}
+
+func NewChallengeFeedbackRepo(db *gorm.DB) *ChallengeFeedbackRepo {
+	return &ChallengeFeedbackRepo{
+		Repository: orz.NewRepository[models.ChallengeFeedback, string](db),
+	}
+}
+
+func (r ChallengeFeedbackRepo) FindByChallengeIdAndUserId(ctx context.Context, challengeId, userId string) (item models.ChallengeFeedback, exists bool, err error) {
+	var items []models.ChallengeFeedback
+	err = r.GetDB(ctx).
+		Where("challenge_id = ? and user_id = ?", challengeId, userId).
+		Limit(1).
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
+// RatingStat 题目的评分统计
+type RatingStat struct {
+	ChallengeId string
+	Rating      float64
+	Count       int64
+}
+
+// GroupRatingByChallengeIdIn 按题目统计平均评分和评价人数
+func (r ChallengeFeedbackRepo) GroupRatingByChallengeIdIn(ctx context.Context, challengeIds []string) (items []RatingStat, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.ChallengeFeedback{}).
+		Select("challenge_id, avg(rating) as rating, count(id) as count").
+		Where("challenge_id in ?", challengeIds).
+		Group("challenge_id").
+		Find(&items).Error
+	return
+}
+
+// DifficultyStat 题目的体感难度投票
+type DifficultyStat struct {
+	ChallengeId string
+	Difficulty  string
+	Count       int64
+}
+
+// GroupDifficultyByChallengeIdIn 按题目和难度统计投票数，未投票的不计入
+func (r ChallengeFeedbackRepo) GroupDifficultyByChallengeIdIn(ctx context.Context, challengeIds []string) (items []DifficultyStat, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.ChallengeFeedback{}).
+		Select("challenge_id, difficulty, count(id) as count").
+		Where("challenge_id in ? and difficulty <> ''", challengeIds).
+		Group("challenge_id, difficulty").
+		Find(&items).Error
+	return
+}
+
+// Paging 分页查询评价，包含用户和题目信息，challengeId 为空时不过滤
+func (r ChallengeFeedbackRepo) Paging(ctx context.Context, offset, limit int, challengeId string) (items []views.FeedbackView, total int64, err error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if challengeId != "" {
+			db = db.Where("challenge_feedbacks.challenge_id = ?", challengeId)
+		}
+		return db
+	}
+	err = r.GetDB(ctx).Model(&models.ChallengeFeedback{}).Scopes(scope).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = r.GetDB(ctx).
+		Model(&models.ChallengeFeedback{}).
+		Select("challenge_feedbacks.*, users.name as user_name, challenges.name as challenge_name").
+		Joins("left join users on users.id = challenge_feedbacks.user_id").
+		Joins("left join challenges on challenges.id = challenge_feedbacks.challenge_id").
+		Scopes(scope).
+		Order("challenge_feedbacks.updated_at desc").
+		Offset(offset).
+		Limit(limit).
+		Find(&items).Error
+	return
+}
+
+func (r ChallengeFeedbackRepo) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	return r.GetDB(ctx).Where("challenge_id = ?", challengeId).Delete(&models.ChallengeFeedback{}).Error
+}
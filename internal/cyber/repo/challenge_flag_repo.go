@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+func NewChallengeFlagRepo(db *gorm.DB) *ChallengeFlagRepo {
+	return &ChallengeFlagRepo{
+		Repository: orz.NewRepository[models.ChallengeFlag, string](db),
+	}
+}
+
+type ChallengeFlagRepo struct {
+	orz.Repository[models.ChallengeFlag, string]
+}
+
+func (r ChallengeFlagRepo) FindByChallengeId(ctx context.Context, challengeId string) (items []models.ChallengeFlag, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ?", challengeId).Order("sort asc, created_at asc").Find(&items).Error
+	return
+}
+
+// ExistsByChallengeId 题目是否配置了阶段
+func (r ChallengeFlagRepo) ExistsByChallengeId(ctx context.Context, challengeId string) (bool, error) {
+	var count int64
+	err := r.GetDB(ctx).
+		Model(&models.ChallengeFlag{}).
+		Where("challenge_id = ?", challengeId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func NewFlagCaptureRepo(db *gorm.DB) *FlagCaptureRepo {
+	return &FlagCaptureRepo{
+		Repository: orz.NewRepository[models.FlagCapture, string](db),
+	}
+}
+
+type FlagCaptureRepo struct {
+	orz.Repository[models.FlagCapture, string]
+}
+
+func (r FlagCaptureRepo) FindByChallengeIdAndUserId(ctx context.Context, challengeId, userId string) (items []models.FlagCapture, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ? and user_id = ?", challengeId, userId).Find(&items).Error
+	return
+}
+
+// SumPointsByUserId 用户提交阶段 Flag 获得的总分
+func (r FlagCaptureRepo) SumPointsByUserId(ctx context.Context, userId string) (sum int64, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.FlagCapture{}).
+		Select("coalesce(sum(points), 0)").
+		Where("user_id = ?", userId).
+		Scan(&sum).Error
+	return
+}
+
+// SumPointsGroupByUserId 每个用户提交阶段 Flag 获得的总分
+func (r FlagCaptureRepo) SumPointsGroupByUserId(ctx context.Context) (data map[string]int64, err error) {
+	var items []SumUserId
+	err = r.GetDB(ctx).
+		Model(&models.FlagCapture{}).
+		Select("user_id, sum(points) as sum").
+		Group("user_id").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	data = make(map[string]int64, len(items))
+	for _, item := range items {
+		data[item.UserId] = item.Sum
+	}
+	return data, nil
+}
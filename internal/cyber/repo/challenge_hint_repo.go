@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+func NewChallengeHintRepo(db *gorm.DB) *ChallengeHintRepo {
+	return &ChallengeHintRepo{
+		Repository: orz.NewRepository[models.ChallengeHint, string](db),
+	}
+}
+
+type ChallengeHintRepo struct {
+	orz.Repository[models.ChallengeHint, string]
+}
+
+func (r ChallengeHintRepo) FindByChallengeId(ctx context.Context, challengeId string) (items []models.ChallengeHint, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ?", challengeId).Order("sort asc, created_at asc").Find(&items).Error
+	return
+}
+
+func NewHintUnlockRepo(db *gorm.DB) *HintUnlockRepo {
+	return &HintUnlockRepo{
+		Repository: orz.NewRepository[models.HintUnlock, string](db),
+	}
+}
+
+type HintUnlockRepo struct {
+	orz.Repository[models.HintUnlock, string]
+}
+
+func (r HintUnlockRepo) FindByChallengeIdAndUserId(ctx context.Context, challengeId, userId string) (items []models.HintUnlock, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ? and user_id = ?", challengeId, userId).Find(&items).Error
+	return
+}
+
+func (r HintUnlockRepo) ExistsByHintIdAndUserId(ctx context.Context, hintId, userId string) (bool, error) {
+	var count int64
+	err := r.GetDB(ctx).
+		Model(&models.HintUnlock{}).
+		Where("hint_id = ? and user_id = ?", hintId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+type SumUserId struct {
+	UserId string `json:"user_id"`
+	Sum    int64  `json:"sum"`
+}
+
+// DeleteByChallengeId 删除题目下全部提示的解锁记录
+func (r HintUnlockRepo) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	return r.GetDB(ctx).Where("challenge_id = ?", challengeId).Delete(&models.HintUnlock{}).Error
+}
+
+// SumCostByUserId 用户解锁提示扣除的总分，已删除的提示不再扣分
+func (r HintUnlockRepo) SumCostByUserId(ctx context.Context, userId string) (sum int64, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.HintUnlock{}).
+		Select("coalesce(sum(hint_unlocks.cost), 0)").
+		Joins("inner join challenge_hints on challenge_hints.id = hint_unlocks.hint_id").
+		Where("hint_unlocks.user_id = ?", userId).
+		Scan(&sum).Error
+	return
+}
+
+// SumCostGroupByUserId 每个用户解锁提示扣除的总分，已删除的提示不再扣分
+func (r HintUnlockRepo) SumCostGroupByUserId(ctx context.Context) (data map[string]int64, err error) {
+	var items []SumUserId
+	err = r.GetDB(ctx).
+		Model(&models.HintUnlock{}).
+		Select("hint_unlocks.user_id, sum(hint_unlocks.cost) as sum").
+		Joins("inner join challenge_hints on challenge_hints.id = hint_unlocks.hint_id").
+		Group("hint_unlocks.user_id").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	data = make(map[string]int64, len(items))
+	for _, item := range items {
+		data[item.UserId] = item.Sum
+	}
+	return data, nil
+}
@@ -27,6 +27,42 @@ func (r *ChallengeRecordRepo) CountByChallengeId(ctx context.Context, challengeI
 	return count, err
 }
 
+// FindInstanceStatusBySubdomain 按子域名查询启动记录及其实例的状态，实例已删除时 status 为空
+func (r *ChallengeRecordRepo) FindInstanceStatusBySubdomain(ctx context.Context, subdomain string) (status models.InstanceStatus, exists bool, err error) {
+	var rows []struct {
+		Status *string
+	}
+	err = r.GetDB(ctx).WithContext(ctx).
+		Table("challenge_records").
+		Select("instances.status").
+		Joins("left join instances on instances.id = challenge_records.instance_id").
+		Where("challenge_records.subdomain = ?", subdomain).
+		Order("challenge_records.created_at desc").
+		Limit(1).
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return "", false, err
+	}
+	if rows[0].Status != nil {
+		status = models.InstanceStatus(*rows[0].Status)
+	}
+	return status, true, nil
+}
+
+// FindFirstByUserIdAndChallengeId 用户最早的一条挑战记录
+func (r *ChallengeRecordRepo) FindFirstByUserIdAndChallengeId(ctx context.Context, userId, challengeId string) (item models.ChallengeRecord, exists bool, err error) {
+	var items []models.ChallengeRecord
+	err = r.GetDB(ctx).
+		Where("user_id = ? and challenge_id = ?", userId, challengeId).
+		Order("created_at asc").
+		Limit(1).
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
 type CountChallengeId struct {
 	Count       int64  `json:"count"`
 	ChallengeId string `json:"challenge_id"`
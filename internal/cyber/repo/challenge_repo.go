@@ -1,6 +1,8 @@
 package repo
 
 import (
+	"context"
+
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/go-orz/orz"
 	"gorm.io/gorm"
@@ -12,6 +14,126 @@ func NewChallengeRepo(db *gorm.DB) *ChallengeRepo {
 	}
 }
 
+// visible 已启用、已上线且未下线的题目
+func visible(now int64) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("enabled = ?", true).
+			Where("release_at = 0 or release_at <= ?", now).
+			Where("hide_at = 0 or hide_at > ?", now)
+	}
+}
+
 type ChallengeRepo struct {
 	orz.Repository[models.Challenge, string]
 }
+
+func (r ChallengeRepo) FindBySlug(ctx context.Context, slug string) (item models.Challenge, exists bool, err error) {
+	var items []models.Challenge
+	err = r.GetDB(ctx).Where("slug = ?", slug).Limit(1).Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
+func (r ChallengeRepo) FindByScoreType(ctx context.Context, scoreType string) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).Where("score_type = ?", scoreType).Find(&items).Error
+	return
+}
+
+// PagingVisible 分页查询玩家可见的题目，now 为毫秒时间戳
+func (r ChallengeRepo) PagingVisible(ctx context.Context, offset, limit int, name, category, difficulty, tag string, now int64) (items []models.Challenge, total int64, err error) {
+	where := func(db *gorm.DB) *gorm.DB {
+		db = visible(now)(db)
+		if name != "" {
+			db = db.Where("name like ?", "%"+name+"%")
+		}
+		if category != "" {
+			db = db.Where("category = ?", category)
+		}
+		if difficulty != "" {
+			db = db.Where("difficulty = ?", difficulty)
+		}
+		if tag != "" {
+			db = db.Where("id in (?)", r.GetDB(ctx).
+				Model(&models.ChallengeTag{}).
+				Select("challenge_tags.challenge_id").
+				Joins("join tags on tags.id = challenge_tags.tag_id").
+				Where("tags.name = ?", tag))
+		}
+		return db
+	}
+
+	db := r.GetDB(ctx)
+	err = db.Model(&models.Challenge{}).Scopes(where).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Scopes(where).Order("sort desc").Offset(offset).Limit(limit).Find(&items).Error
+	return items, total, err
+}
+
+// FindReleasedBetween 上线时间在 (from, to] 之间的启用题目
+func (r ChallengeRepo) FindReleasedBetween(ctx context.Context, from, to int64) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).
+		Where("enabled = ? and release_at > ? and release_at <= ?", true, from, to).
+		Find(&items).Error
+	return
+}
+
+// FindUpcoming 尚未上线的题目，按上线时间排序
+func (r ChallengeRepo) FindUpcoming(ctx context.Context, now int64) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).
+		Where("release_at > ?", now).
+		Order("release_at asc").
+		Find(&items).Error
+	return
+}
+
+// FindCategories 题目使用的全部类别名称
+func (r ChallengeRepo) FindCategories(ctx context.Context) (names []string, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Challenge{}).
+		Distinct("category").
+		Where("category <> ''").
+		Pluck("category", &names).Error
+	return
+}
+
+func (r ChallengeRepo) CountByCategory(ctx context.Context, category string) (int64, error) {
+	var count int64
+	err := r.GetDB(ctx).
+		Model(&models.Challenge{}).
+		Where("category = ?", category).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateCategory 类别改名时同步修改题目
+func (r ChallengeRepo) UpdateCategory(ctx context.Context, old, new string) error {
+	return r.GetDB(ctx).
+		Model(&models.Challenge{}).
+		Where("category = ?", old).
+		Update("category", new).Error
+}
+
+// FindVisible 玩家可见的全部题目，now 为毫秒时间戳
+func (r ChallengeRepo) FindVisible(ctx context.Context, now int64) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).Scopes(visible(now)).Find(&items).Error
+	return
+}
+
+// FindEnabledWithSlug 由题目包导入且启用中的题目
+func (r ChallengeRepo) FindEnabledWithSlug(ctx context.Context) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).Where("slug <> '' and enabled = ?", true).Find(&items).Error
+	return
+}
+
+// FindEnabledWithChecker 启用中且配置了健康检查器的题目
+func (r ChallengeRepo) FindEnabledWithChecker(ctx context.Context) (items []models.Challenge, err error) {
+	err = r.GetDB(ctx).
+		Where("enabled = ? and image_id <> ''", true).
+		Where("checker_image <> '' or checker_script <> ''").
+		Find(&items).Error
+	return
+}
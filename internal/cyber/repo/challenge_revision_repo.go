@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type ChallengeRevisionRepo struct {
+	orz.Repository[models.ChallengeRevision, string]
+}
+
+func NewChallengeRevisionRepo(db *gorm.DB) *ChallengeRevisionRepo {
+	return &ChallengeRevisionRepo{
+		Repository: orz.NewRepository[models.ChallengeRevision, string](db),
+	}
+}
+
+// FindByChallengeId 题目的修订记录，按版本号倒序，不包含快照
+func (r ChallengeRevisionRepo) FindByChallengeId(ctx context.Context, challengeId string) (items []models.ChallengeRevision, err error) {
+	err = r.GetDB(ctx).
+		Omit("snapshot").
+		Where("challenge_id = ?", challengeId).
+		Order("version desc").
+		Find(&items).Error
+	return
+}
+
+func (r ChallengeRevisionRepo) MaxVersionByChallengeId(ctx context.Context, challengeId string) (int64, error) {
+	var version int64
+	err := r.GetDB(ctx).
+		Model(&models.ChallengeRevision{}).
+		Select("coalesce(max(version), 0)").
+		Where("challenge_id = ?", challengeId).
+		Scan(&version).Error
+	return version, err
+}
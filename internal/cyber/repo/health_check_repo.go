@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type HealthCheckRepo struct {
+	orz.Repository[models.HealthCheck, string]
+}
+
+func NewHealthCheckRepo(db *gorm.DB) *HealthCheckRepo {
+	return &HealthCheckRepo{
+		Repository: orz.NewRepository[models.HealthCheck, string](db),
+	}
+}
+
+// FindByChallengeId 题目最近的检查记录
+func (r HealthCheckRepo) FindByChallengeId(ctx context.Context, challengeId string, limit int) (items []models.HealthCheck, err error) {
+	err = r.GetDB(ctx).
+		Where("challenge_id = ?", challengeId).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&items).Error
+	return
+}
+
+// FindLatest 每个题目最近一次的检查结果，failedOnly 时只返回未通过的
+func (r HealthCheckRepo) FindLatest(ctx context.Context, failedOnly bool) (items []views.ChallengeHealth, err error) {
+	db := r.GetDB(ctx)
+	latest := db.Model(&models.HealthCheck{}).
+		Select("challenge_id, max(created_at) as created_at").
+		Group("challenge_id")
+	query := db.Model(&models.HealthCheck{}).
+		Select("health_checks.challenge_id, challenges.name as challenge_name, health_checks.passed, health_checks.exit_code, health_checks.error, health_checks.created_at").
+		Joins("join (?) latest on latest.challenge_id = health_checks.challenge_id and latest.created_at = health_checks.created_at", latest).
+		Joins("join challenges on challenges.id = health_checks.challenge_id")
+	if failedOnly {
+		query = query.Where("health_checks.passed = ?", false)
+	}
+	err = query.Order("health_checks.created_at desc").Find(&items).Error
+	return
+}
+
+// DeleteBefore 删除过期的检查记录
+func (r HealthCheckRepo) DeleteBefore(ctx context.Context, createdAt int64) error {
+	return r.GetDB(ctx).Where("created_at < ?", createdAt).Delete(&models.HealthCheck{}).Error
+}
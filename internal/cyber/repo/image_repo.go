@@ -1,6 +1,8 @@
 package repo
 
 import (
+	"context"
+
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/go-orz/orz"
 	"gorm.io/gorm"
@@ -15,3 +17,16 @@ func NewImageRepo(db *gorm.DB) *ImageRepo {
 type ImageRepo struct {
 	orz.Repository[models.Image, string]
 }
+
+func (r ImageRepo) FindByRegistry(ctx context.Context, registry string) (item models.Image, exists bool, err error) {
+	var items []models.Image
+	err = r.GetDB(ctx).Where("registry = ?", registry).Limit(1).Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
+func (r ImageRepo) UpdateColumnsByStatus(ctx context.Context, status models.ImageStatus, columns orz.Map) error {
+	return r.GetDB(ctx).Model(&models.Image{}).Where("status = ?", status).Updates(columns).Error
+}
@@ -22,3 +22,19 @@ func (r InstanceRepo) FindByUserIdAndChallengeId(ctx context.Context, userId, ch
 	err = r.GetDB(ctx).Where("user_id = ? and challenge_id = ?", userId, challengeId).Find(&items).Error
 	return
 }
+
+// FindRoutable 查询可被网关转发的实例
+func (r InstanceRepo) FindRoutable(ctx context.Context) (items []models.Instance, err error) {
+	err = r.GetDB(ctx).
+		Where("status = ? and subdomain <> '' and target <> ''", models.InstanceStatusRunning).
+		Find(&items).Error
+	return
+}
+
+func (r InstanceRepo) ExistsBySubdomainAndStatusIn(ctx context.Context, subdomain string, statuses []models.InstanceStatus) (bool, error) {
+	var count int64
+	err := r.GetDB(ctx).Model(&models.Instance{}).
+		Where("subdomain = ? and status in ?", subdomain, statuses).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -23,7 +23,7 @@ func (r RankRepo) List(ctx context.Context, limit int) (items []views.RankView,
 		Model(&models.Rank{}).
 		Select("ranks.score, ranks.total_time as total_time, ranks.total_time_str as total_time_str, ranks.user_id as user_id, users.name as user_name, users.avatar as user_avatar").
 		Joins("left join users on users.id = ranks.user_id").
-		Order("ranks.score desc, ranks.total_time asc, users.name asc").
+		Order("ranks.score desc, ranks.partial asc, ranks.total_time asc, users.name asc").
 		Limit(limit).
 		Find(&items).Error
 	return
@@ -63,7 +63,7 @@ func (r SolveRepo) PagingWithUserAndChallenge(ctx context.Context, offset, limit
 	orderBy := fmt.Sprintf("solves.%s %s", sortField, sortOrder)
 	err = db.
 		Model(&models.Solve{}).
-		Select("solves.id, solves.user_id, solves.challenge_id, solves.start_at, solves.solved_at, solves.used_time, solves.used_time_str, users.name as user_name, users.avatar as user_avatar, challenges.name as challenge_name, challenges.points as points").
+		Select("solves.id, solves.user_id, solves.challenge_id, solves.start_at, solves.solved_at, solves.used_time, solves.used_time_str, solves.bonus, solves.solve_order, users.name as user_name, users.avatar as user_avatar, challenges.name as challenge_name, solves.points as points").
 		Joins("left join users on users.id = solves.user_id").
 		Joins("left join challenges on challenges.id = solves.challenge_id").
 		Order(orderBy).
@@ -129,3 +129,64 @@ func (r SolveRepo) GroupCountByChallengeIdInAndUserId(ctx context.Context, chall
 	}
 	return data, err
 }
+
+// ClearStagedPoints 配置了阶段的题目由阶段分数组成总分，把这些题目通关记录的分值清零
+func (r SolveRepo) ClearStagedPoints(ctx context.Context) error {
+	return r.GetDB(ctx).
+		Model(&models.Solve{}).
+		Where("points <> 0 and challenge_id in (?)", r.GetDB(ctx).Model(&models.ChallengeFlag{}).Select("challenge_id")).
+		UpdateColumn("points", 0).Error
+}
+
+// UpdatePointsByChallengeId 把题目全部通关记录的分值更新为当前分值
+func (r SolveRepo) UpdatePointsByChallengeId(ctx context.Context, challengeId string, points int64) error {
+	return r.GetDB(ctx).
+		Model(&models.Solve{}).
+		Where("challenge_id = ? and points <> ?", challengeId, points).
+		UpdateColumn("points", points).Error
+}
+
+func (r SolveRepo) FindChallengeIdsWithoutOrder(ctx context.Context) (challengeIds []string, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Solve{}).
+		Where("solve_order = 0").
+		Distinct("challenge_id").
+		Pluck("challenge_id", &challengeIds).Error
+	return
+}
+
+func (r SolveRepo) FindByChallengeIdOrderBySolvedAt(ctx context.Context, challengeId string) (items []models.Solve, err error) {
+	err = r.GetDB(ctx).Where("challenge_id = ?", challengeId).Order("solved_at asc").Find(&items).Error
+	return
+}
+
+// FindByUserId 用户的全部通关记录，包含题目信息
+func (r SolveRepo) FindByUserId(ctx context.Context, userId string) (items []views.SolveView, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Solve{}).
+		Select("solves.*, challenges.name as challenge_name").
+		Joins("left join challenges on challenges.id = solves.challenge_id").
+		Where("solves.user_id = ?", userId).
+		Order("solves.solved_at desc").
+		Find(&items).Error
+	return
+}
+
+// SolvedChallenge 用户通关的题目
+type SolvedChallenge struct {
+	ChallengeId string
+	Category    string
+	Points      int64
+	Bonus       int64
+}
+
+// FindSolvedChallengesByUserId 用户通关的题目及其类别
+func (r SolveRepo) FindSolvedChallengesByUserId(ctx context.Context, userId string) (items []SolvedChallenge, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Solve{}).
+		Select("solves.challenge_id, challenges.category, solves.points, solves.bonus").
+		Joins("left join challenges on challenges.id = solves.challenge_id").
+		Where("solves.user_id = ?", userId).
+		Find(&items).Error
+	return
+}
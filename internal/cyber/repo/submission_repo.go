@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type SubmissionRepo struct {
+	orz.Repository[models.Submission, string]
+}
+
+func NewSubmissionRepo(db *gorm.DB) *SubmissionRepo {
+	return &SubmissionRepo{
+		Repository: orz.NewRepository[models.Submission, string](db),
+	}
+}
+
+// SubmissionFilter 提交记录的查询条件，零值表示不过滤
+type SubmissionFilter struct {
+	ChallengeId string
+	UserId      string
+	IP          string
+	Correct     *bool
+	Start       int64 // 提交时间不早于，单位：毫秒
+	End         int64 // 提交时间早于，单位：毫秒
+}
+
+func (f SubmissionFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.ChallengeId != "" {
+		db = db.Where("submissions.challenge_id = ?", f.ChallengeId)
+	}
+	if f.UserId != "" {
+		db = db.Where("submissions.user_id = ?", f.UserId)
+	}
+	if f.IP != "" {
+		db = db.Where("submissions.ip = ?", f.IP)
+	}
+	if f.Correct != nil {
+		db = db.Where("submissions.correct = ?", *f.Correct)
+	}
+	if f.Start > 0 {
+		db = db.Where("submissions.created_at >= ?", f.Start)
+	}
+	if f.End > 0 {
+		db = db.Where("submissions.created_at < ?", f.End)
+	}
+	return db
+}
+
+func (r SubmissionRepo) Count(ctx context.Context, filter SubmissionFilter) (total int64, err error) {
+	err = r.GetDB(ctx).Model(&models.Submission{}).Scopes(filter.scope).Count(&total).Error
+	return
+}
+
+// Find 按提交时间倒序查询，包含用户和题目信息
+func (r SubmissionRepo) Find(ctx context.Context, offset, limit int, filter SubmissionFilter) (items []views.SubmissionView, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Submission{}).
+		Select("submissions.*, users.name as user_name, challenges.name as challenge_name").
+		Joins("left join users on users.id = submissions.user_id").
+		Joins("left join challenges on challenges.id = submissions.challenge_id").
+		Scopes(filter.scope).
+		Order("submissions.created_at desc, submissions.id").
+		Offset(offset).
+		Limit(limit).
+		Find(&items).Error
+	return
+}
+
+// StatsByChallengeId 题目的提交次数、正确次数和提交人数
+func (r SubmissionRepo) StatsByChallengeId(ctx context.Context, challengeId string) (stats views.SubmissionStats, err error) {
+	var row struct {
+		Total   int64
+		Correct int64
+		Users   int64
+	}
+	err = r.GetDB(ctx).
+		Model(&models.Submission{}).
+		Select("count(id) as total, coalesce(sum(case when correct then 1 else 0 end), 0) as correct, count(distinct user_id) as users").
+		Where("challenge_id = ?", challengeId).
+		Scan(&row).Error
+	stats.Total, stats.Correct, stats.Users = row.Total, row.Correct, row.Users
+	return
+}
+
+// GroupWrongByChallengeId 按提交内容统计错误答案，次数多的在前
+func (r SubmissionRepo) GroupWrongByChallengeId(ctx context.Context, challengeId string, limit int) (items []views.WrongAnswer, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Submission{}).
+		Select("flag, count(id) as count, count(distinct user_id) as users").
+		Where("challenge_id = ? and correct = ? and limited = ?", challengeId, false, false).
+		Group("flag").
+		Order("count desc, users desc").
+		Limit(limit).
+		Find(&items).Error
+	return
+}
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type TagRepo struct {
+	orz.Repository[models.Tag, string]
+}
+
+func NewTagRepo(db *gorm.DB) *TagRepo {
+	return &TagRepo{
+		Repository: orz.NewRepository[models.Tag, string](db),
+	}
+}
+
+func (r TagRepo) FindByNameIn(ctx context.Context, names []string) (items []models.Tag, err error) {
+	err = r.GetDB(ctx).Where("name in ?", names).Find(&items).Error
+	return
+}
+
+// TagCount 标签及其题目数量
+type TagCount struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// FindAllWithCount 全部标签及关联的题目数量，按名称排序
+func (r TagRepo) FindAllWithCount(ctx context.Context) (items []TagCount, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.Tag{}).
+		Select("tags.id, tags.name, count(challenge_tags.id) as count").
+		Joins("left join challenge_tags on challenge_tags.tag_id = tags.id").
+		Group("tags.id, tags.name").
+		Order("tags.name asc").
+		Find(&items).Error
+	return
+}
+
+type ChallengeTagRepo struct {
+	orz.Repository[models.ChallengeTag, string]
+}
+
+func NewChallengeTagRepo(db *gorm.DB) *ChallengeTagRepo {
+	return &ChallengeTagRepo{
+		Repository: orz.NewRepository[models.ChallengeTag, string](db),
+	}
+}
+
+func (r ChallengeTagRepo) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	return r.GetDB(ctx).Where("challenge_id = ?", challengeId).Delete(&models.ChallengeTag{}).Error
+}
+
+func (r ChallengeTagRepo) DeleteByTagId(ctx context.Context, tagId string) error {
+	return r.GetDB(ctx).Where("tag_id = ?", tagId).Delete(&models.ChallengeTag{}).Error
+}
+
+// ChallengeTagName 题目的标签名称
+type ChallengeTagName struct {
+	ChallengeId string
+	Name        string
+}
+
+// FindNamesByChallengeIdIn 题目的标签名称，按名称排序
+func (r ChallengeTagRepo) FindNamesByChallengeIdIn(ctx context.Context, challengeIds []string) (items []ChallengeTagName, err error) {
+	err = r.GetDB(ctx).
+		Model(&models.ChallengeTag{}).
+		Select("challenge_tags.challenge_id, tags.name").
+		Joins("join tags on tags.id = challenge_tags.tag_id").
+		Where("challenge_tags.challenge_id in ?", challengeIds).
+		Order("tags.name asc").
+		Find(&items).Error
+	return
+}
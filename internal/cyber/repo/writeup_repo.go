@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type WriteupRepo struct {
+	orz.Repository[models.Writeup, string]
+}
+
+func NewWriteupRepo(db *gorm.DB) *WriteupRepo {
+	return &WriteupRepo{
+		Repository: orz.NewRepository[models.Writeup, string](db),
+	}
+}
+
+func (r WriteupRepo) FindByChallengeIdAndUserId(ctx context.Context, challengeId, userId string) (item models.Writeup, exists bool, err error) {
+	var items []models.Writeup
+	err = r.GetDB(ctx).
+		Where("challenge_id = ? and user_id = ?", challengeId, userId).
+		Limit(1).
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return item, false, err
+	}
+	return items[0], true, nil
+}
+
+// writeupViews 题解包含作者和题目信息
+func (r WriteupRepo) writeupViews(ctx context.Context) *gorm.DB {
+	return r.GetDB(ctx).
+		Model(&models.Writeup{}).
+		Select("writeups.*, users.name as user_name, users.avatar as user_avatar, challenges.name as challenge_name").
+		Joins("left join users on users.id = writeups.user_id").
+		Joins("left join challenges on challenges.id = writeups.challenge_id")
+}
+
+// FindByChallengeIdAndStatus 题目下某个状态的题解，按提交时间排序
+func (r WriteupRepo) FindByChallengeIdAndStatus(ctx context.Context, challengeId, status string) (items []views.WriteupView, err error) {
+	err = r.writeupViews(ctx).
+		Where("writeups.challenge_id = ? and writeups.status = ?", challengeId, status).
+		Order("writeups.created_at asc").
+		Find(&items).Error
+	return
+}
+
+// Paging 分页查询题解，status、challengeId 为空时不过滤
+func (r WriteupRepo) Paging(ctx context.Context, offset, limit int, status, challengeId string) (items []views.WriteupView, total int64, err error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if status != "" {
+			db = db.Where("writeups.status = ?", status)
+		}
+		if challengeId != "" {
+			db = db.Where("writeups.challenge_id = ?", challengeId)
+		}
+		return db
+	}
+	err = r.GetDB(ctx).Model(&models.Writeup{}).Scopes(scope).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = r.writeupViews(ctx).
+		Scopes(scope).
+		Order("writeups.updated_at desc").
+		Offset(offset).
+		Limit(limit).
+		Find(&items).Error
+	return
+}
+
+func (r WriteupRepo) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	return r.GetDB(ctx).Where("challenge_id = ?", challengeId).Delete(&models.Writeup{}).Error
+}
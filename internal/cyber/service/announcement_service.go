@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// releaseLookback 只为最近一天内上线的题目发布公告，避免服务停机后补发过期公告
+const releaseLookback = 24 * time.Hour
+
+type AnnouncementService struct {
+	*orz.Service
+	*repo.AnnouncementRepo
+	logger        *zap.Logger
+	challengeRepo *repo.ChallengeRepo
+}
+
+func NewAnnouncementService(db *gorm.DB, logger *zap.Logger) *AnnouncementService {
+	return &AnnouncementService{
+		Service:          orz.NewService(db),
+		AnnouncementRepo: repo.NewAnnouncementRepo(db),
+		logger:           logger,
+		challengeRepo:    repo.NewChallengeRepo(db),
+	}
+}
+
+// AnnounceReleases 为已到上线时间的题目发布公告，同一次上线只发布一次
+func (s *AnnouncementService) AnnounceReleases(ctx context.Context) error {
+	now := time.Now()
+	challenges, err := s.challengeRepo.FindReleasedBetween(ctx, now.Add(-releaseLookback).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		if !challenge.Visible(now.UnixMilli()) {
+			continue
+		}
+		exists, err := s.AnnouncementRepo.ExistsByChallengeIdAndReleaseAt(ctx, challenge.ID, challenge.ReleaseAt)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		announcement := models.Announcement{
+			ID:          uuid.NewString(),
+			Title:       fmt.Sprintf("新题目上线：%s", challenge.Name),
+			Content:     fmt.Sprintf("%s 类题目「%s」已上线，分值 %d。", challenge.Category, challenge.Name, challenge.Points),
+			ChallengeId: challenge.ID,
+			ReleaseAt:   challenge.ReleaseAt,
+		}
+		if err := s.AnnouncementRepo.Create(ctx, &announcement); err != nil {
+			return err
+		}
+		s.logger.Info("challenge released", zap.String("id", challenge.ID), zap.String("name", challenge.Name))
+	}
+	return nil
+}
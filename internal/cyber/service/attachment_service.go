@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/storage"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAttachmentDir        = "data/attachments"
+	defaultAttachmentSecretFile = "data/attachment.secret"
+	defaultAttachmentExpires    = 300
+	defaultAttachmentMaxSize    = 100
+)
+
+func NewAttachmentService(db *gorm.DB, conf *config.Config) (*AttachmentService, error) {
+	attachment := conf.Attachment
+	var store storage.Storage
+	switch attachment.Storage {
+	case "", config.StorageLocal:
+		dir := attachment.Dir
+		if dir == "" {
+			dir = defaultAttachmentDir
+		}
+		store = storage.NewLocalStorage(dir)
+	default:
+		return nil, fmt.Errorf("不支持的附件存储: %s", attachment.Storage)
+	}
+
+	secret := []byte(attachment.Secret)
+	if len(secret) == 0 {
+		var err error
+		secret, err = loadOrCreateSecret(defaultAttachmentSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载附件签名密钥失败: %w", err)
+		}
+	}
+	expires := time.Duration(attachment.Expires) * time.Second
+	if expires <= 0 {
+		expires = defaultAttachmentExpires * time.Second
+	}
+	maxSize := attachment.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultAttachmentMaxSize
+	}
+
+	return &AttachmentService{
+		Service:                 orz.NewService(db),
+		ChallengeAttachmentRepo: repo.NewChallengeAttachmentRepo(db),
+		storage:                 store,
+		secret:                  secret,
+		expires:                 expires,
+		maxSize:                 maxSize << 20,
+	}, nil
+}
+
+// loadOrCreateSecret 从文件加载签名密钥，不存在时随机生成并保存，重启后已签发的链接仍然有效
+func loadOrCreateSecret(p string) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid secret file: %s", p)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(p, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// AttachmentService 题目附件
+type AttachmentService struct {
+	*orz.Service
+	*repo.ChallengeAttachmentRepo
+
+	storage storage.Storage
+	secret  []byte
+	expires time.Duration
+	maxSize int64 // 单位：字节
+}
+
+// Upload 上传附件，同一题目下同名的附件会被覆盖
+func (s *AttachmentService) Upload(ctx context.Context, challengeId, name string, r io.Reader) (*models.ChallengeAttachment, error) {
+	attachment, oldKey, err := s.Put(ctx, challengeId, name, r)
+	if err != nil {
+		return nil, err
+	}
+	s.Purge(ctx, oldKey)
+	return attachment, nil
+}
+
+// Put 写入附件文件并保存记录，返回被覆盖的旧文件路径；在事务中调用时，由调用方在事务提交后删除旧文件
+func (s *AttachmentService) Put(ctx context.Context, challengeId, name string, r io.Reader) (*models.ChallengeAttachment, string, error) {
+	name = cleanAttachmentName(name)
+	if name == "" {
+		return nil, "", xe.ErrInvalidParams
+	}
+
+	items, err := s.ChallengeAttachmentRepo.FindByChallengeIdAndName(ctx, challengeId, name)
+	if err != nil {
+		return nil, "", err
+	}
+	var attachment models.ChallengeAttachment
+	if len(items) > 0 {
+		attachment = items[0]
+	} else {
+		attachment = models.ChallengeAttachment{
+			ID:          uuid.NewString(),
+			ChallengeId: challengeId,
+			Name:        name,
+		}
+	}
+	// 每次上传使用新的路径，写入失败时不影响原有文件
+	oldKey := attachment.StorageKey
+	key := path.Join(challengeId, uuid.NewString())
+
+	hash := sha256.New()
+	size, err := s.storage.Put(ctx, key, io.TeeReader(io.LimitReader(r, s.maxSize+1), hash))
+	if err != nil {
+		return nil, "", err
+	}
+	if size > s.maxSize {
+		_ = s.storage.Delete(ctx, key)
+		return nil, "", xe.ErrAttachmentTooLarge
+	}
+
+	attachment.Size = size
+	attachment.Sha256 = hex.EncodeToString(hash.Sum(nil))
+	attachment.StorageKey = key
+	if len(items) > 0 {
+		err = s.ChallengeAttachmentRepo.Save(ctx, &attachment)
+	} else {
+		err = s.ChallengeAttachmentRepo.Create(ctx, &attachment)
+	}
+	if err != nil {
+		_ = s.storage.Delete(ctx, key)
+		return nil, "", err
+	}
+	return &attachment, oldKey, nil
+}
+
+// Purge 删除不再被引用的附件文件，忽略空路径和删除失败
+func (s *AttachmentService) Purge(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key != "" {
+			_ = s.storage.Delete(ctx, key)
+		}
+	}
+}
+
+// cleanAttachmentName 保留附件在题目包中的相对路径，去掉 .. 等不安全的部分
+func cleanAttachmentName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean("/" + name)
+	return strings.TrimPrefix(name, "/")
+}
+
+// Delete 删除附件及其文件
+func (s *AttachmentService) Delete(ctx context.Context, id string) error {
+	attachment, exists, err := s.ChallengeAttachmentRepo.FindByIdExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrAttachmentNotFound
+	}
+	if err := s.ChallengeAttachmentRepo.DeleteById(ctx, id); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, attachment.StorageKey)
+}
+
+// DeleteByChallengeId 删除题目的全部附件记录，返回的文件不受事务控制，需要在事务提交后通过 Purge 删除
+func (s *AttachmentService) DeleteByChallengeId(ctx context.Context, challengeId string) ([]string, error) {
+	items, err := s.ChallengeAttachmentRepo.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return nil, err
+	}
+	var keys = make([]string, 0, len(items))
+	for _, item := range items {
+		if err := s.ChallengeAttachmentRepo.DeleteById(ctx, item.ID); err != nil {
+			return nil, err
+		}
+		keys = append(keys, item.StorageKey)
+	}
+	return keys, nil
+}
+
+// ReadAll 读取附件的全部内容，用于导出题目包
+func (s *AttachmentService) ReadAll(ctx context.Context, attachment models.ChallengeAttachment) ([]byte, error) {
+	rc, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Open 打开附件用于下载
+func (s *AttachmentService) Open(ctx context.Context, id string) (*models.ChallengeAttachment, io.ReadSeekCloser, error) {
+	attachment, exists, err := s.ChallengeAttachmentRepo.FindByIdExists(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, xe.ErrAttachmentNotFound
+	}
+	rc, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, xe.ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+	return &attachment, rc, nil
+}
+
+// SignedURL 生成附件的临时下载地址
+func (s *AttachmentService) SignedURL(id string) (string, int64) {
+	expiresAt := time.Now().Add(s.expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", s.sign(id, expiresAt))
+	return fmt.Sprintf("/api/attachments/%s/download?%s", id, query.Encode()), expiresAt
+}
+
+// VerifySignature 校验下载地址的签名和有效期
+func (s *AttachmentService) VerifySignature(id, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return xe.ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expiresAt))) {
+		return xe.ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *AttachmentService) sign(id string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id + "." + strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"sync"
+)
+
+// BuildLog 进行中的镜像构建日志，可以有多个读者同时跟随
+type BuildLog struct {
+	mu      sync.Mutex
+	lines   []string
+	done    bool
+	changed chan struct{}
+}
+
+func newBuildLog() *BuildLog {
+	return &BuildLog{changed: make(chan struct{})}
+}
+
+func (l *BuildLog) append(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *BuildLog) finish() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.done = true
+	close(l.changed)
+}
+
+// Read 读取第 offset 行之后的日志；未结束时返回的通道会在有新日志或构建结束时关闭
+func (l *BuildLog) Read(offset int) (lines []string, done bool, changed <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if offset < len(l.lines) {
+		lines = append(lines, l.lines[offset:]...)
+	}
+	return lines, l.done, l.changed
+}
+
+func (l *BuildLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CategoryService 题目类别
+type CategoryService struct {
+	*orz.Service
+	*repo.CategoryRepo
+	challengeRepo *repo.ChallengeRepo
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{
+		Service:       orz.NewService(db),
+		CategoryRepo:  repo.NewCategoryRepo(db),
+		challengeRepo: repo.NewChallengeRepo(db),
+	}
+}
+
+// Create 新建类别，名称不能重复
+func (s *CategoryService) Create(ctx context.Context, item *models.Category) error {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		return xe.ErrInvalidCategory
+	}
+	_, exists, err := s.CategoryRepo.FindByName(ctx, item.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return xe.ErrCategoryDuplicate
+	}
+	item.ID = uuid.NewString()
+	return s.CategoryRepo.Create(ctx, item)
+}
+
+// Update 修改类别，改名时同步修改题目的类别
+func (s *CategoryService) Update(ctx context.Context, item models.Category) error {
+	item.Name = strings.TrimSpace(item.Name)
+	return s.Transaction(ctx, func(ctx context.Context) error {
+		old, exists, err := s.CategoryRepo.FindByIdExists(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return xe.ErrCategoryNotFound
+		}
+		if item.Name == "" {
+			return xe.ErrInvalidCategory
+		}
+		if item.Name != old.Name {
+			_, exists, err := s.CategoryRepo.FindByName(ctx, item.Name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return xe.ErrCategoryDuplicate
+			}
+			if err := s.challengeRepo.UpdateCategory(ctx, old.Name, item.Name); err != nil {
+				return err
+			}
+		}
+		return s.CategoryRepo.UpdateColumnsById(ctx, item.ID, orz.Map{
+			"name": item.Name,
+			"icon": item.Icon,
+			"sort": item.Sort,
+		})
+	})
+}
+
+// Delete 删除类别，类别下还有题目时不能删除
+func (s *CategoryService) Delete(ctx context.Context, id string) error {
+	item, exists, err := s.CategoryRepo.FindByIdExists(ctx, id)
+	if err != nil || !exists {
+		return err
+	}
+	count, err := s.challengeRepo.CountByCategory(ctx, item.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return xe.ErrCategoryInUse
+	}
+	return s.CategoryRepo.DeleteById(ctx, id)
+}
+
+// Ensure 类别不存在时新建，用于导入题目
+func (s *CategoryService) Ensure(ctx context.Context, name string) error {
+	if name == "" {
+		return nil
+	}
+	_, exists, err := s.CategoryRepo.FindByName(ctx, name)
+	if err != nil || exists {
+		return err
+	}
+	return s.CategoryRepo.Create(ctx, &models.Category{ID: uuid.NewString(), Name: name})
+}
+
+// Sync 为题目中已使用但尚未管理的类别新建记录
+func (s *CategoryService) Sync(ctx context.Context) error {
+	names, err := s.challengeRepo.FindCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := s.Ensure(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
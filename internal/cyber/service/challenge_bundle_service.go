@@ -0,0 +1,707 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/richtext"
+	"github.com/dushixiang/cyberpoc/pkg/tools"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	BundleActionCreate    = "create"
+	BundleActionUpdate    = "update"
+	BundleActionUnchanged = "unchanged"
+)
+
+// ImportPlan 导入题目包的执行计划，dry-run 时只返回计划不写入
+type ImportPlan struct {
+	Slug        string              `json:"slug"`
+	Action      string              `json:"action"`       // create、update、unchanged
+	ChallengeId string              `json:"challenge_id"` // 新建时为空
+	Image       string              `json:"image"`        // 镜像 create、unchanged，无镜像时为空
+	ImageId     string              `json:"image_id"`
+	Changes     []tools.FieldChange `json:"changes"`
+	Attachments []AttachmentPlan    `json:"attachments"`
+
+	prerequisites []string // 按 slug 解析出的前置题目ID
+	uploaded      []string // 本次写入的附件文件，事务回滚时删除
+	replaced      []string // 被覆盖的附件文件，事务提交后删除
+}
+
+// AttachmentPlan 附件的变更，按文件名匹配，摘要一致时不重新上传
+type AttachmentPlan struct {
+	Name   string `json:"name"`
+	Action string `json:"action"` // create、update、unchanged
+}
+
+func NewChallengeBundleService(db *gorm.DB, challengeService *ChallengeService, categoryService *CategoryService, tagService *TagService,
+	challengeRevisionService *ChallengeRevisionService, attachmentService *AttachmentService, hintService *HintService, flagService *FlagService) *ChallengeBundleService {
+	return &ChallengeBundleService{
+		Service:                  orz.NewService(db),
+		challengeRepo:            repo.NewChallengeRepo(db),
+		imageRepo:                repo.NewImageRepo(db),
+		challengeService:         challengeService,
+		categoryService:          categoryService,
+		tagService:               tagService,
+		challengeRevisionService: challengeRevisionService,
+		attachmentService:        attachmentService,
+		hintService:              hintService,
+		flagService:              flagService,
+	}
+}
+
+// ChallengeBundleService 题目包的导入导出
+type ChallengeBundleService struct {
+	*orz.Service
+	challengeRepo            *repo.ChallengeRepo
+	imageRepo                *repo.ImageRepo
+	challengeService         *ChallengeService
+	categoryService          *CategoryService
+	tagService               *TagService
+	challengeRevisionService *ChallengeRevisionService
+	attachmentService        *AttachmentService
+	hintService              *HintService
+	flagService              *FlagService
+}
+
+// Export 导出题目为题目包，只读取不修改数据，未设置 slug 的题目使用 ID 作为 slug
+func (s *ChallengeBundleService) Export(ctx context.Context, id string) (*bundle.Bundle, error) {
+	challenge, exists, err := s.challengeRepo.FindByIdExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, xe.ErrChallengeNotFound
+	}
+	slug := challenge.Slug
+	if slug == "" {
+		slug = challenge.ID
+	}
+	b := &bundle.Bundle{
+		Manifest: bundle.Manifest{
+			Slug:        slug,
+			Name:        challenge.Name,
+			Category:    challenge.Category,
+			Difficulty:  challenge.Difficulty,
+			Points:      challenge.Points,
+			ScoreType:   challenge.ScoreType,
+			Minimum:     challenge.Minimum,
+			Decay:       challenge.Decay,
+			BonusType:   challenge.BonusType,
+			Bonuses:     challenge.Bonuses,
+			Flag:        challenge.Flag,
+			DynamicFlag: challenge.DynamicFlag,
+			Enabled:     challenge.Enabled,
+			Duration:    challenge.Duration,
+			Html:        challenge.Html,
+			Sort:        challenge.Sort,
+		},
+		Description: challenge.Description,
+	}
+	b.Manifest.FlagMatch = challenge.FlagMatch
+	b.Manifest.FlagStripWrapper = challenge.FlagStripWrapper
+	b.Manifest.UnlockScore = challenge.UnlockScore
+	b.Manifest.UnlockCategory = challenge.UnlockCategory
+	b.Manifest.UnlockCategoryCount = challenge.UnlockCategoryCount
+	b.Manifest.CheckerImage = challenge.CheckerImage
+	b.Manifest.CheckerScript = challenge.CheckerScript
+	b.Manifest.SubmitLimit = challenge.SubmitLimit
+	b.Manifest.Prerequisites, err = s.prerequisiteSlugs(ctx, challenge.Prerequisites)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.ImageId != "" {
+		image, exists, err := s.imageRepo.FindByIdExists(ctx, challenge.ImageId)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			b.Manifest.Image = &bundle.ImageRef{
+				Name:        image.Name,
+				Registry:    image.Registry,
+				CpuLimit:    image.CpuLimit,
+				MemoryLimit: image.MemoryLimit,
+				Exposed:     image.Exposed,
+				Protocol:    image.Protocol,
+			}
+		}
+	}
+
+	b.Manifest.Tags, err = s.tagService.Names(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	hints, err := s.hintService.FindByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hint := range hints {
+		b.Manifest.Hints = append(b.Manifest.Hints, bundle.HintRef{
+			Content: hint.Content,
+			Cost:    hint.Cost,
+		})
+	}
+
+	stages, err := s.flagService.FindByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	b.Manifest.Flags = toFlagRefs(stages)
+
+	attachments, err := s.attachmentService.FindByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		data, err := s.attachmentService.ReadAll(ctx, attachment)
+		if err != nil {
+			return nil, err
+		}
+		b.Attachments = append(b.Attachments, bundle.Attachment{
+			Name: attachment.Name,
+			Data: data,
+		})
+	}
+	return b, nil
+}
+
+// Import 按 slug 导入题目包，已存在的题目更新，不存在的新建；镜像按 registry 匹配，不存在时新建
+func (s *ChallengeBundleService) Import(ctx context.Context, b *bundle.Bundle, dryRun bool, authorId string) (*ImportPlan, error) {
+	if errs := b.Validate(); len(errs) > 0 {
+		return nil, &bundle.ValidationError{Errors: errs}
+	}
+	return s.importBundle(ctx, b, dryRun, authorId, nil)
+}
+
+// importBundle 在一个事务中导入已校验的题目包，pending 为同一批次中将要新建的题目 slug
+func (s *ChallengeBundleService) importBundle(ctx context.Context, b *bundle.Bundle, dryRun bool, authorId string, pending map[string]bool) (*ImportPlan, error) {
+	var plan *ImportPlan
+	err := s.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		plan, err = s.plan(ctx, b, pending)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		return s.apply(ctx, b, plan, authorId)
+	})
+	if err != nil {
+		// 记录已回滚，新写入的文件不再被引用
+		if plan != nil {
+			s.attachmentService.Purge(ctx, plan.uploaded...)
+		}
+		return nil, err
+	}
+	s.attachmentService.Purge(ctx, plan.replaced...)
+	return plan, nil
+}
+
+func (s *ChallengeBundleService) plan(ctx context.Context, b *bundle.Bundle, pending map[string]bool) (*ImportPlan, error) {
+	m := b.Manifest
+	plan := &ImportPlan{Slug: m.Slug}
+
+	for i, slug := range m.Prerequisites {
+		challenge, exists, err := s.challengeRepo.FindBySlug(ctx, slug)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			// 导出时未设置 slug 的前置题目使用的是 ID
+			challenge, exists, err = s.challengeRepo.FindByIdExists(ctx, slug)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !exists && pending[slug] {
+			// dry-run 时同一批次中的前置题目尚未创建
+			continue
+		}
+		if !exists {
+			return nil, &bundle.ValidationError{Errors: []bundle.FieldError{{
+				Field:   fmt.Sprintf("prerequisites[%d]", i),
+				Message: "题目不存在: " + slug,
+			}}}
+		}
+		plan.prerequisites = append(plan.prerequisites, challenge.ID)
+	}
+
+	if m.Image != nil {
+		image, exists, err := s.imageRepo.FindByRegistry(ctx, m.Image.Registry)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			plan.Image = BundleActionUnchanged
+			plan.ImageId = image.ID
+		} else {
+			plan.Image = BundleActionCreate
+		}
+	}
+
+	existing, exists, err := s.challengeRepo.FindBySlug(ctx, m.Slug)
+	if err != nil {
+		return nil, err
+	}
+	// 更新前置题目后不能形成循环依赖
+	err = s.challengeService.CheckPrerequisites(ctx, models.Challenge{ID: existing.ID, Prerequisites: plan.prerequisites})
+	if err != nil {
+		return nil, &bundle.ValidationError{Errors: []bundle.FieldError{{Field: "prerequisites", Message: err.Error()}}}
+	}
+	if !exists {
+		plan.Action = BundleActionCreate
+		for _, attachment := range b.Attachments {
+			plan.Attachments = append(plan.Attachments, AttachmentPlan{Name: attachment.Name, Action: BundleActionCreate})
+		}
+		return plan, nil
+	}
+
+	plan.ChallengeId = existing.ID
+	updated := s.toChallenge(existing, b, plan)
+	// 新镜像尚未创建，按 registry 展示变更；前置题目按 slug 展示变更
+	ignore := []string{"id", "created_at", "updated_at", "prerequisites"}
+	if plan.Image == BundleActionCreate {
+		ignore = append(ignore, "image_id")
+	}
+	plan.Changes = tools.DiffStruct(existing, updated, ignore...)
+	// 与阶段 Flag 一样只提示变更，不展示 Flag 内容
+	for i := range plan.Changes {
+		if plan.Changes[i].Field == "flag" {
+			plan.Changes[i].Old = redactedFlag
+			plan.Changes[i].New = redactedFlag
+		}
+	}
+	if plan.Image == BundleActionCreate {
+		plan.Changes = append(plan.Changes, tools.FieldChange{
+			Field: "image_id",
+			Old:   existing.ImageId,
+			New:   m.Image.Registry,
+		})
+	}
+
+	if !slices.Equal(existing.Prerequisites, updated.Prerequisites) {
+		old, err := s.prerequisiteSlugs(ctx, existing.Prerequisites)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, tools.FieldChange{
+			Field: "prerequisites",
+			Old:   strings.Join(old, ", "),
+			New:   strings.Join(m.Prerequisites, ", "),
+		})
+	}
+
+	tags, err := s.tagService.Names(ctx, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	newTags, _ := NormalizeTags(m.Tags)
+	slices.Sort(tags)
+	slices.Sort(newTags)
+	if !slices.Equal(tags, newTags) {
+		plan.Changes = append(plan.Changes, tools.FieldChange{
+			Field: "tags",
+			Old:   strings.Join(tags, ", "),
+			New:   strings.Join(newTags, ", "),
+		})
+	}
+
+	hints, err := s.hintService.FindByChallengeId(ctx, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	var oldHints = make([]bundle.HintRef, 0, len(hints))
+	for _, hint := range hints {
+		oldHints = append(oldHints, bundle.HintRef{Content: hint.Content, Cost: hint.Cost})
+	}
+	if change, changed := diffHints(oldHints, m.Hints); changed {
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	stages, err := s.flagService.FindByChallengeId(ctx, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	if change, changed := diffFlags(toFlagRefs(stages), m.Flags); changed {
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	attachments, err := s.attachmentService.FindByChallengeId(ctx, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	var digests = make(map[string]string, len(attachments))
+	for _, attachment := range attachments {
+		digests[attachment.Name] = attachment.Sha256
+	}
+	var attachmentChanged bool
+	for _, attachment := range b.Attachments {
+		sum := sha256.Sum256(attachment.Data)
+		digest, ok := digests[attachment.Name]
+		action := BundleActionUnchanged
+		switch {
+		case !ok:
+			action = BundleActionCreate
+		case digest != hex.EncodeToString(sum[:]):
+			action = BundleActionUpdate
+		}
+		if action != BundleActionUnchanged {
+			attachmentChanged = true
+		}
+		plan.Attachments = append(plan.Attachments, AttachmentPlan{Name: attachment.Name, Action: action})
+	}
+
+	if len(plan.Changes) == 0 && !attachmentChanged {
+		plan.Action = BundleActionUnchanged
+	} else {
+		plan.Action = BundleActionUpdate
+	}
+	return plan, nil
+}
+
+func (s *ChallengeBundleService) apply(ctx context.Context, b *bundle.Bundle, plan *ImportPlan, authorId string) error {
+	if plan.Image == BundleActionCreate {
+		ref := b.Manifest.Image
+		image := models.Image{
+			ID:          uuid.NewString(),
+			Name:        ref.Name,
+			Registry:    ref.Registry,
+			CpuLimit:    ref.CpuLimit,
+			MemoryLimit: ref.MemoryLimit,
+			Exposed:     ref.Exposed,
+			Protocol:    ref.Protocol,
+			Status:      models.ImageStatusUnknown,
+		}
+		if image.Name == "" {
+			image.Name = ref.Registry
+		}
+		if err := s.imageRepo.Create(ctx, &image); err != nil {
+			return err
+		}
+		plan.ImageId = image.ID
+	}
+
+	if err := s.categoryService.Ensure(ctx, b.Manifest.Category); err != nil {
+		return err
+	}
+
+	switch plan.Action {
+	case BundleActionCreate:
+		challenge := s.toChallenge(models.Challenge{ID: uuid.NewString()}, b, plan)
+		if err := s.challengeRepo.Create(ctx, &challenge); err != nil {
+			return err
+		}
+		plan.ChallengeId = challenge.ID
+		if err := s.challengeRevisionService.Record(ctx, challenge.ID, authorId, models.RevisionActionImport, nil); err != nil {
+			return err
+		}
+	case BundleActionUpdate:
+		existing, err := s.challengeRepo.FindById(ctx, plan.ChallengeId)
+		if err != nil {
+			return err
+		}
+		challenge := s.toChallenge(existing, b, plan)
+		if err := s.challengeRepo.Save(ctx, &challenge); err != nil {
+			return err
+		}
+		if err := s.challengeService.SyncScoreType(ctx, existing); err != nil {
+			return err
+		}
+		if err := s.challengeRevisionService.Record(ctx, challenge.ID, authorId, models.RevisionActionImport, &existing); err != nil {
+			return err
+		}
+	}
+
+	if plan.Action == BundleActionCreate || plan.changed("tags") {
+		if err := s.tagService.SetTags(ctx, plan.ChallengeId, b.Manifest.Tags); err != nil {
+			return err
+		}
+	}
+
+	if plan.Action == BundleActionCreate || plan.changed("hints") {
+		var hints = make([]models.ChallengeHint, 0, len(b.Manifest.Hints))
+		for _, hint := range b.Manifest.Hints {
+			hints = append(hints, models.ChallengeHint{Content: hint.Content, Cost: hint.Cost})
+		}
+		if err := s.hintService.ReplaceByChallengeId(ctx, plan.ChallengeId, hints); err != nil {
+			return err
+		}
+	}
+
+	if plan.Action == BundleActionCreate || plan.changed("flags") {
+		var stages = make([]models.ChallengeFlag, 0, len(b.Manifest.Flags))
+		for _, flag := range b.Manifest.Flags {
+			stages = append(stages, models.ChallengeFlag{
+				Name:     flag.Name,
+				Flag:     flag.Flag,
+				Dynamic:  flag.Dynamic,
+				Env:      flag.Env,
+				Points:   flag.Points,
+				Optional: flag.Optional,
+
+				Match:        flag.Match,
+				StripWrapper: flag.StripWrapper,
+			})
+		}
+		if err := s.flagService.ReplaceByChallengeId(ctx, plan.ChallengeId, stages); err != nil {
+			return err
+		}
+	}
+
+	var actions = make(map[string]string, len(plan.Attachments))
+	for _, attachment := range plan.Attachments {
+		actions[attachment.Name] = attachment.Action
+	}
+	for _, attachment := range b.Attachments {
+		if actions[attachment.Name] == BundleActionUnchanged {
+			continue
+		}
+		item, oldKey, err := s.attachmentService.Put(ctx, plan.ChallengeId, attachment.Name, bytes.NewReader(attachment.Data))
+		if err != nil {
+			return err
+		}
+		plan.uploaded = append(plan.uploaded, item.StorageKey)
+		plan.replaced = append(plan.replaced, oldKey)
+	}
+	return nil
+}
+
+// SyncPlan 同步一批题目包的执行计划
+type SyncPlan struct {
+	Challenges []*ImportPlan  `json:"challenges"`
+	Disabled   []SyncDisabled `json:"disabled"` // prune 时停用的题目
+}
+
+// Changed 是否有需要写入的变更
+func (p *SyncPlan) Changed() bool {
+	if len(p.Disabled) > 0 {
+		return true
+	}
+	for _, item := range p.Challenges {
+		if item.Action != BundleActionUnchanged || item.Image == BundleActionCreate {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncDisabled 不在本批次中而被停用的题目
+type SyncDisabled struct {
+	ChallengeId string `json:"challenge_id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+}
+
+// Sync 按 slug 同步一批题目包，前置题目先导入；prune 时停用不在本批次中的、由题目包导入的题目。
+// 每个题目包在各自的事务中导入，中途失败时已导入的题目保留，修正后重新执行即可
+func (s *ChallengeBundleService) Sync(ctx context.Context, bundles []*bundle.Bundle, dryRun, prune bool, authorId string) (*SyncPlan, error) {
+	var errs []bundle.FieldError
+	for i, b := range bundles {
+		prefix := b.Manifest.Slug
+		if prefix == "" {
+			prefix = fmt.Sprintf("bundles[%d]", i)
+		}
+		for _, fe := range b.Validate() {
+			errs = append(errs, bundle.FieldError{Field: prefix + "." + fe.Field, Message: fe.Message})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &bundle.ValidationError{Errors: errs}
+	}
+	ordered, err := bundle.Order(bundles)
+	if err != nil {
+		return nil, &bundle.ValidationError{Errors: []bundle.FieldError{{Field: "prerequisites", Message: err.Error()}}}
+	}
+
+	var (
+		plan    = &SyncPlan{}
+		pending = make(map[string]bool)
+		slugs   = make(map[string]bool, len(ordered))
+	)
+	for _, b := range ordered {
+		slug := b.Manifest.Slug
+		slugs[slug] = true
+		item, err := s.importBundle(ctx, b, dryRun, authorId, pending)
+		if err != nil {
+			var ve *bundle.ValidationError
+			if errors.As(err, &ve) {
+				for i := range ve.Errors {
+					ve.Errors[i].Field = slug + "." + ve.Errors[i].Field
+				}
+				return nil, ve
+			}
+			return nil, fmt.Errorf("%s: %w", slug, err)
+		}
+		if item.Action == BundleActionCreate {
+			pending[slug] = true
+		}
+		plan.Challenges = append(plan.Challenges, item)
+	}
+
+	if !prune {
+		return plan, nil
+	}
+	challenges, err := s.challengeRepo.FindEnabledWithSlug(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, challenge := range challenges {
+		if slugs[challenge.Slug] {
+			continue
+		}
+		plan.Disabled = append(plan.Disabled, SyncDisabled{
+			ChallengeId: challenge.ID,
+			Slug:        challenge.Slug,
+			Name:        challenge.Name,
+		})
+		if dryRun {
+			continue
+		}
+		if err := s.challengeRepo.UpdateColumnsById(ctx, challenge.ID, orz.Map{"enabled": false}); err != nil {
+			return nil, err
+		}
+		if err := s.challengeRevisionService.Record(ctx, challenge.ID, authorId, models.RevisionActionSync, &challenge); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// toChallenge 把题目包的内容覆盖到题目上
+func (s *ChallengeBundleService) toChallenge(challenge models.Challenge, b *bundle.Bundle, plan *ImportPlan) models.Challenge {
+	m := b.Manifest
+	challenge.Slug = m.Slug
+	challenge.Name = m.Name
+	challenge.Description = b.Description
+	challenge.Category = m.Category
+	challenge.Difficulty = m.Difficulty
+	challenge.Points = m.Points
+	challenge.ScoreType = m.ScoreType
+	challenge.Minimum = m.Minimum
+	challenge.Decay = m.Decay
+	challenge.BonusType = m.BonusType
+	// 空切片和 nil 视为相同，避免产生无意义的变更
+	if len(m.Bonuses) > 0 || len(challenge.Bonuses) > 0 {
+		challenge.Bonuses = m.Bonuses
+	}
+	challenge.Flag = m.Flag
+	challenge.DynamicFlag = m.DynamicFlag
+	challenge.FlagMatch = m.FlagMatch
+	challenge.FlagStripWrapper = m.FlagStripWrapper
+	challenge.Enabled = m.Enabled
+	challenge.ImageId = plan.ImageId
+	challenge.Duration = m.Duration
+	challenge.Html = richtext.Sanitize(m.Html)
+	challenge.Sort = m.Sort
+	if len(plan.prerequisites) > 0 || len(challenge.Prerequisites) > 0 {
+		challenge.Prerequisites = plan.prerequisites
+	}
+	challenge.UnlockScore = m.UnlockScore
+	challenge.UnlockCategory = m.UnlockCategory
+	challenge.UnlockCategoryCount = m.UnlockCategoryCount
+	challenge.CheckerImage = m.CheckerImage
+	challenge.CheckerScript = m.CheckerScript
+	challenge.SubmitLimit = m.SubmitLimit
+	return challenge
+}
+
+// prerequisiteSlugs 前置题目ID转换为 slug，未设置 slug 的题目使用 ID，已删除的题目忽略
+func (s *ChallengeBundleService) prerequisiteSlugs(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	challenges, err := s.challengeRepo.FindByIdIn(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var slugs = make(map[string]string, len(challenges))
+	for _, challenge := range challenges {
+		slugs[challenge.ID] = challenge.Slug
+		if challenge.Slug == "" {
+			slugs[challenge.ID] = challenge.ID
+		}
+	}
+	var items = make([]string, 0, len(ids))
+	for _, id := range ids {
+		if slug, ok := slugs[id]; ok {
+			items = append(items, slug)
+		}
+	}
+	return items, nil
+}
+
+func (p *ImportPlan) changed(field string) bool {
+	for _, change := range p.Changes {
+		if change.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// diffHints 提示按顺序整体比较，变更时展示每条提示的分数和内容
+func diffHints(old, new []bundle.HintRef) (tools.FieldChange, bool) {
+	if slices.Equal(old, new) {
+		return tools.FieldChange{}, false
+	}
+	format := func(hints []bundle.HintRef) string {
+		var items = make([]string, 0, len(hints))
+		for _, hint := range hints {
+			items = append(items, fmt.Sprintf("(%d) %s", hint.Cost, hint.Content))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return tools.FieldChange{Field: "hints", Old: format(old), New: format(new)}, true
+}
+
+func toFlagRefs(stages []models.ChallengeFlag) []bundle.FlagRef {
+	var refs []bundle.FlagRef
+	for _, stage := range stages {
+		refs = append(refs, bundle.FlagRef{
+			Name:     stage.Name,
+			Flag:     stage.Flag,
+			Dynamic:  stage.Dynamic,
+			Env:      stage.Env,
+			Points:   stage.Points,
+			Optional: stage.Optional,
+
+			Match:        stage.Match,
+			StripWrapper: stage.StripWrapper,
+		})
+	}
+	return refs
+}
+
+// redactedFlag 变更预览中代替 Flag 内容
+const redactedFlag = "******"
+
+// diffFlags Flag 阶段按顺序整体比较，变更时展示阶段名称和分数，不展示 Flag 内容
+func diffFlags(old, new []bundle.FlagRef) (tools.FieldChange, bool) {
+	if slices.Equal(old, new) {
+		return tools.FieldChange{}, false
+	}
+	format := func(flags []bundle.FlagRef) string {
+		var items = make([]string, 0, len(flags))
+		for _, flag := range flags {
+			items = append(items, fmt.Sprintf("%s(%d)", flag.Name, flag.Points))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return tools.FieldChange{Field: "flags", Old: format(old), New: format(new)}, true
+}
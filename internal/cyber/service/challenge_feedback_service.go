@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxFeedbackComment 评论的最大字符数
+const maxFeedbackComment = 2000
+
+type ChallengeFeedbackService struct {
+	*orz.Service
+	*repo.ChallengeFeedbackRepo
+	challengeRecordRepo *repo.ChallengeRecordRepo
+}
+
+func NewChallengeFeedbackService(db *gorm.DB) *ChallengeFeedbackService {
+	return &ChallengeFeedbackService{
+		Service:               orz.NewService(db),
+		ChallengeFeedbackRepo: repo.NewChallengeFeedbackRepo(db),
+		challengeRecordRepo:   repo.NewChallengeRecordRepo(db),
+	}
+}
+
+// Submit 提交或修改评价，只有参与过题目的玩家才能评价
+func (s *ChallengeFeedbackService) Submit(ctx context.Context, challengeId, userId string, rating int, difficulty, comment string) (models.ChallengeFeedback, error) {
+	comment = strings.TrimSpace(comment)
+	if rating < 1 || rating > 5 || utf8.RuneCountInString(comment) > maxFeedbackComment {
+		return models.ChallengeFeedback{}, xe.ErrInvalidFeedback
+	}
+	if difficulty != "" && !slices.Contains(models.Difficulties, difficulty) {
+		return models.ChallengeFeedback{}, xe.ErrInvalidFeedback
+	}
+	_, attempted, err := s.challengeRecordRepo.FindFirstByUserIdAndChallengeId(ctx, userId, challengeId)
+	if err != nil {
+		return models.ChallengeFeedback{}, err
+	}
+	if !attempted {
+		return models.ChallengeFeedback{}, xe.ErrFeedbackNotAttempted
+	}
+
+	item, exists, err := s.ChallengeFeedbackRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+	if err != nil {
+		return item, err
+	}
+	if !exists {
+		item.ID = uuid.NewString()
+		item.ChallengeId = challengeId
+		item.UserId = userId
+	}
+	item.Rating = rating
+	item.Difficulty = difficulty
+	item.Comment = comment
+	if !exists {
+		return item, s.ChallengeFeedbackRepo.Create(ctx, &item)
+	}
+	return item, s.ChallengeFeedbackRepo.Save(ctx, &item)
+}
+
+// Mine 玩家自己的评价，未评价时 exists 为 false
+func (s *ChallengeFeedbackService) Mine(ctx context.Context, challengeId, userId string) (models.ChallengeFeedback, bool, error) {
+	return s.ChallengeFeedbackRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+}
+
+// Summaries 按题目汇总评分和体感难度投票
+func (s *ChallengeFeedbackService) Summaries(ctx context.Context, challengeIds []string) (map[string]views.FeedbackSummary, error) {
+	ratings, err := s.ChallengeFeedbackRepo.GroupRatingByChallengeIdIn(ctx, challengeIds)
+	if err != nil {
+		return nil, err
+	}
+	difficulties, err := s.ChallengeFeedbackRepo.GroupDifficultyByChallengeIdIn(ctx, challengeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var data = make(map[string]views.FeedbackSummary, len(ratings))
+	for _, item := range ratings {
+		data[item.ChallengeId] = views.FeedbackSummary{
+			Rating:          math.Round(item.Rating*100) / 100,
+			RatingCount:     item.Count,
+			DifficultyVotes: make(map[string]int64),
+		}
+	}
+	for _, item := range difficulties {
+		summary, ok := data[item.ChallengeId]
+		if !ok {
+			continue
+		}
+		summary.DifficultyVotes[item.Difficulty] = item.Count
+	}
+	return data, nil
+}
+
+// Summary 单个题目的评价汇总
+func (s *ChallengeFeedbackService) Summary(ctx context.Context, challengeId string) (views.FeedbackSummary, error) {
+	data, err := s.Summaries(ctx, []string{challengeId})
+	if err != nil {
+		return views.FeedbackSummary{}, err
+	}
+	summary, ok := data[challengeId]
+	if !ok {
+		summary.DifficultyVotes = make(map[string]int64)
+	}
+	return summary, nil
+}
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"context"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/identity"
 	"github.com/go-orz/orz"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -17,3 +23,23 @@ func NewChallengeRecordService(db *gorm.DB) *ChallengeRecordService {
 		ChallengeRecordRepo: repo.NewChallengeRecordRepo(db),
 	}
 }
+
+// RecordStart 无需启动环境的题目在用户开始挑战或第一次提交时记录挑战开始，用于计算通关耗时
+func (s *ChallengeRecordService) RecordStart(ctx context.Context, userId string, challenge models.Challenge) error {
+	_, exists, err := s.ChallengeRecordRepo.FindFirstByUserIdAndChallengeId(ctx, userId, challenge.ID)
+	if err != nil || exists {
+		return err
+	}
+	user, err := identity.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return s.ChallengeRecordRepo.Create(ctx, &models.ChallengeRecord{
+		ID:            uuid.NewString(),
+		UserId:        userId,
+		UserName:      user.Name,
+		ChallengeId:   challenge.ID,
+		ChallengeName: challenge.Name,
+		CreatedAt:     time.Now().UnixMilli(),
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/tools"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// revisionIgnore 不记录变更的字段
+var revisionIgnore = []string{"id", "created_at", "updated_at"}
+
+// ChallengeRevisionService 题目修订记录
+type ChallengeRevisionService struct {
+	*orz.Service
+	*repo.ChallengeRevisionRepo
+	challengeRepo *repo.ChallengeRepo
+}
+
+func NewChallengeRevisionService(db *gorm.DB) *ChallengeRevisionService {
+	return &ChallengeRevisionService{
+		Service:               orz.NewService(db),
+		ChallengeRevisionRepo: repo.NewChallengeRevisionRepo(db),
+		challengeRepo:         repo.NewChallengeRepo(db),
+	}
+}
+
+// Record 记录题目保存后的快照，before 为保存前的题目，新建时为 nil；内容没有变化时不记录
+func (s *ChallengeRevisionService) Record(ctx context.Context, challengeId, authorId, action string, before *models.Challenge) error {
+	after, err := s.challengeRepo.FindById(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	var old models.Challenge
+	if before != nil {
+		old = *before
+	}
+	changes := tools.DiffStruct(old, after, revisionIgnore...)
+	if before != nil && len(changes) == 0 {
+		return nil
+	}
+
+	var authorName string
+	if authorId != "" {
+		user, err := identity.GetUserById(ctx, authorId)
+		if err != nil {
+			return err
+		}
+		authorName = user.Name
+	}
+
+	version, err := s.ChallengeRevisionRepo.MaxVersionByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	return s.ChallengeRevisionRepo.Create(ctx, &models.ChallengeRevision{
+		ID:          uuid.NewString(),
+		ChallengeId: challengeId,
+		Version:     version + 1,
+		Action:      action,
+		AuthorId:    authorId,
+		AuthorName:  authorName,
+		Changes:     changes,
+		Snapshot:    datatypes.NewJSONType(after),
+	})
+}
+
+// FindRevision 查找题目的某个修订
+func (s *ChallengeRevisionService) FindRevision(ctx context.Context, challengeId, id string) (models.ChallengeRevision, error) {
+	revision, exists, err := s.ChallengeRevisionRepo.FindByIdExists(ctx, id)
+	if err != nil {
+		return revision, err
+	}
+	if !exists || revision.ChallengeId != challengeId {
+		return revision, xe.ErrRevisionNotFound
+	}
+	return revision, nil
+}
+
+// Diff 比较两个修订的快照
+func (s *ChallengeRevisionService) Diff(ctx context.Context, challengeId, fromId, toId string) ([]tools.FieldChange, error) {
+	from, err := s.FindRevision(ctx, challengeId, fromId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.FindRevision(ctx, challengeId, toId)
+	if err != nil {
+		return nil, err
+	}
+	return tools.DiffStruct(from.Snapshot.Data(), to.Snapshot.Data(), revisionIgnore...), nil
+}
+
+// Snapshot 修订保存的题目，用于恢复；题目已被删除时会重新创建
+func (s *ChallengeRevisionService) Snapshot(ctx context.Context, challengeId, id string) (models.Challenge, error) {
+	revision, err := s.FindRevision(ctx, challengeId, id)
+	if err != nil {
+		return models.Challenge{}, err
+	}
+	challenge := revision.Snapshot.Data()
+	challenge.ID = challengeId
+	return challenge, nil
+}
@@ -2,21 +2,43 @@ package service
 
 import (
 	"context"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/dushixiang/cyberpoc/internal/cyber/bundle"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/flagmatch"
+	"github.com/dushixiang/cyberpoc/pkg/tools"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type ChallengeService struct {
 	*orz.Service
 	*repo.ChallengeRepo
+	solveRepo         *repo.SolveRepo
+	categoryRepo      *repo.CategoryRepo
+	challengeTagRepo  *repo.ChallengeTagRepo
+	challengeFlagRepo *repo.ChallengeFlagRepo
+	flagCaptureRepo   *repo.FlagCaptureRepo
+	hintUnlockRepo    *repo.HintUnlockRepo
 }
 
 func NewChallengeService(db *gorm.DB) *ChallengeService {
 	return &ChallengeService{
-		Service:       orz.NewService(db),
-		ChallengeRepo: repo.NewChallengeRepo(db),
+		Service:           orz.NewService(db),
+		ChallengeRepo:     repo.NewChallengeRepo(db),
+		solveRepo:         repo.NewSolveRepo(db),
+		categoryRepo:      repo.NewCategoryRepo(db),
+		challengeTagRepo:  repo.NewChallengeTagRepo(db),
+		challengeFlagRepo: repo.NewChallengeFlagRepo(db),
+		flagCaptureRepo:   repo.NewFlagCaptureRepo(db),
+		hintUnlockRepo:    repo.NewHintUnlockRepo(db),
 	}
 }
 
@@ -25,3 +47,362 @@ func (s *ChallengeService) ExistsById(ctx context.Context, id string) (bool, err
 	_, exists, err := s.ChallengeRepo.FindByIdExists(ctx, id)
 	return exists, err
 }
+
+// Create 新建题目，未设置 slug 时使用 ID 作为 slug，slug 为唯一索引，不能留空
+func (s *ChallengeService) Create(ctx context.Context, item *models.Challenge) error {
+	if item.Slug == "" {
+		item.Slug = item.ID
+	}
+	return s.ChallengeRepo.Create(ctx, item)
+}
+
+// SyncScoreType 题目从动态计分改为静态计分时，把已衰减的通关分值恢复为题目分值，before 为修改前的题目；
+// 静态题目修改分值不影响已经获得的分数
+func (s *ChallengeService) SyncScoreType(ctx context.Context, before models.Challenge) error {
+	if !before.Dynamic() {
+		return nil
+	}
+	after, err := s.ChallengeRepo.FindById(ctx, before.ID)
+	if err != nil {
+		return err
+	}
+	if after.Dynamic() {
+		return nil
+	}
+	staged, err := s.challengeFlagRepo.ExistsByChallengeId(ctx, after.ID)
+	if err != nil {
+		return err
+	}
+	return s.solveRepo.UpdatePointsByChallengeId(ctx, after.ID, after.SolvePoints(0, staged))
+}
+
+// CheckScoring 校验计分方式，动态计分需要 0 <= minimum <= points 且 decay > 0
+func (s *ChallengeService) CheckScoring(item models.Challenge) error {
+	switch item.ScoreType {
+	case "", models.ScoreTypeStatic:
+		return nil
+	case models.ScoreTypeDynamic:
+		if item.Decay <= 0 || item.Minimum < 0 || item.Minimum > item.Points {
+			return xe.ErrInvalidScoring
+		}
+		return nil
+	default:
+		return xe.ErrInvalidScoring
+	}
+}
+
+// CheckFlagMatch 校验题目 Flag 的匹配方式
+func (s *ChallengeService) CheckFlagMatch(item models.Challenge) error {
+	return checkFlagMatch(item.FlagMatch, item.Flag, item.DynamicFlag)
+}
+
+// CheckImage 未配置镜像的题目不启动环境，不能使用动态Flag；配置了镜像的题目会注入 Flag，不能使用正则匹配
+func (s *ChallengeService) CheckImage(item models.Challenge) error {
+	if item.DynamicFlag && !item.RequiresInstance() {
+		return xe.ErrDynamicFlagNoImage
+	}
+	if item.FlagMatch == flagmatch.Regex && item.RequiresInstance() {
+		return xe.ErrRegexFlagWithImage
+	}
+	if item.HasChecker() && !item.RequiresInstance() {
+		return xe.ErrCheckerNoImage
+	}
+	return nil
+}
+
+// CheckSlug 校验 slug 格式与题目包一致且未被其他题目使用，为空时新建题目使用 ID
+func (s *ChallengeService) CheckSlug(ctx context.Context, item models.Challenge) error {
+	if item.Slug == "" {
+		return nil
+	}
+	if !bundle.ValidSlug(item.Slug) {
+		return xe.ErrInvalidSlug
+	}
+	existing, exists, err := s.ChallengeRepo.FindBySlug(ctx, item.Slug)
+	if err != nil {
+		return err
+	}
+	if exists && existing.ID != item.ID {
+		return xe.ErrSlugAlreadyUsed
+	}
+	return nil
+}
+
+// CheckClassification 校验难度等级和类别，类别需要已在类别管理中创建
+func (s *ChallengeService) CheckClassification(ctx context.Context, item models.Challenge) error {
+	if !slices.Contains(models.Difficulties, item.Difficulty) {
+		return xe.ErrInvalidDifficulty
+	}
+	if item.Category == "" {
+		return nil
+	}
+	_, exists, err := s.categoryRepo.FindByName(ctx, item.Category)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrCategoryNotFound
+	}
+	return nil
+}
+
+// CheckBonus 校验前 N 名解题奖励
+func (s *ChallengeService) CheckBonus(item models.Challenge) error {
+	switch item.BonusType {
+	case "", models.BonusTypePoints, models.BonusTypePercent:
+	default:
+		return xe.ErrInvalidBonus
+	}
+	for _, bonus := range item.Bonuses {
+		if bonus < 0 {
+			return xe.ErrInvalidBonus
+		}
+	}
+	return nil
+}
+
+// UnlockProgress 用户的解锁进度
+type UnlockProgress struct {
+	Solved     map[string]bool  // 已通关的题目
+	Score      int64            // 得分，与排行榜一致，包含解题奖励、阶段分数并扣除解锁提示的分数
+	Categories map[string]int64 // 各类别已通关的题目数量
+}
+
+// Unlocked 题目的解锁条件是否全部满足
+func (p UnlockProgress) Unlocked(challenge models.Challenge) bool {
+	for _, id := range challenge.Prerequisites {
+		if !p.Solved[id] {
+			return false
+		}
+	}
+	if p.Score < challenge.UnlockScore {
+		return false
+	}
+	return p.Categories[challenge.UnlockCategory] >= challenge.UnlockCategoryCount
+}
+
+// Progress 用户的解锁进度，得分与排行榜使用同一算法，userId 为空时视为没有通关任何题目
+func (s *ChallengeService) Progress(ctx context.Context, userId string) (UnlockProgress, error) {
+	var progress = UnlockProgress{
+		Solved:     make(map[string]bool),
+		Categories: make(map[string]int64),
+	}
+	if userId == "" {
+		return progress, nil
+	}
+	items, err := s.solveRepo.FindSolvedChallengesByUserId(ctx, userId)
+	if err != nil {
+		return progress, err
+	}
+	var solved int64
+	for _, item := range items {
+		if progress.Solved[item.ChallengeId] {
+			continue
+		}
+		progress.Solved[item.ChallengeId] = true
+		solved += item.Points + item.Bonus
+		progress.Categories[item.Category]++
+	}
+	captured, err := s.flagCaptureRepo.SumPointsByUserId(ctx, userId)
+	if err != nil {
+		return progress, err
+	}
+	cost, err := s.hintUnlockRepo.SumCostByUserId(ctx, userId)
+	if err != nil {
+		return progress, err
+	}
+	progress.Score = userScore(solved, captured, cost)
+	return progress, nil
+}
+
+// CheckUnlocked 用户未满足题目的解锁条件时返回错误
+func (s *ChallengeService) CheckUnlocked(ctx context.Context, userId string, challenge models.Challenge) error {
+	if !challenge.Conditional() {
+		return nil
+	}
+	progress, err := s.Progress(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !progress.Unlocked(challenge) {
+		return xe.ErrChallengeLocked
+	}
+	return nil
+}
+
+// CheckPrerequisites 校验解锁条件，前置题目需要存在且不能形成循环依赖
+func (s *ChallengeService) CheckPrerequisites(ctx context.Context, item models.Challenge) error {
+	if item.UnlockScore < 0 || item.UnlockCategoryCount < 0 {
+		return xe.ErrInvalidPrerequisite
+	}
+	if len(item.Prerequisites) == 0 {
+		return nil
+	}
+	challenges, err := s.ChallengeRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	var edges = make(map[string][]string, len(challenges)+1)
+	for _, challenge := range challenges {
+		edges[challenge.ID] = challenge.Prerequisites
+	}
+	for _, id := range item.Prerequisites {
+		if _, ok := edges[id]; !ok || id == item.ID {
+			return xe.ErrInvalidPrerequisite
+		}
+	}
+	edges[item.ID] = item.Prerequisites
+	if len(tools.FindCycles(edges)) > 0 {
+		return xe.ErrInvalidPrerequisite
+	}
+	return nil
+}
+
+// RemovePrerequisite 题目删除后从其他题目的前置题目中移除，避免依赖它的题目永远无法解锁
+func (s *ChallengeService) RemovePrerequisite(ctx context.Context, id string) error {
+	challenges, err := s.ChallengeRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		if !slices.Contains(challenge.Prerequisites, id) {
+			continue
+		}
+		prerequisites := slices.DeleteFunc(slices.Clone(challenge.Prerequisites), func(item string) bool {
+			return item == id
+		})
+		if err := s.ChallengeRepo.UpdateColumnsById(ctx, challenge.ID, orz.Map{
+			"prerequisites": datatypes.JSONSlice[string](prerequisites),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Graph 题目依赖图，边由前置题目指向解锁的题目
+func (s *ChallengeService) Graph(ctx context.Context) (*views.ChallengeGraph, error) {
+	challenges, err := s.ChallengeRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var graph = &views.ChallengeGraph{
+		Nodes:  make([]views.ChallengeNode, 0, len(challenges)),
+		Edges:  make([]views.ChallengeEdge, 0),
+		Cycles: make([][]string, 0),
+	}
+	var edges = make(map[string][]string, len(challenges))
+	for _, challenge := range challenges {
+		graph.Nodes = append(graph.Nodes, views.ChallengeNode{
+			ID:                  challenge.ID,
+			Name:                challenge.Name,
+			Category:            challenge.Category,
+			Enabled:             challenge.Enabled,
+			UnlockScore:         challenge.UnlockScore,
+			UnlockCategory:      challenge.UnlockCategory,
+			UnlockCategoryCount: challenge.UnlockCategoryCount,
+		})
+		for _, id := range challenge.Prerequisites {
+			graph.Edges = append(graph.Edges, views.ChallengeEdge{From: id, To: challenge.ID})
+		}
+		edges[challenge.ID] = challenge.Prerequisites
+	}
+	graph.Cycles = append(graph.Cycles, tools.FindCycles(edges)...)
+	return graph, nil
+}
+
+// ChallengeMatch 搜索结果
+type ChallengeMatch struct {
+	Challenge models.Challenge
+	Tags      []string
+	Score     int
+}
+
+// Search 在玩家可见的题目中搜索，按名称、标签、类别、描述的匹配程度排序，每个关键词都需要命中；
+// 用户未解锁的题目不搜索描述，避免通过是否命中还原描述
+func (s *ChallengeService) Search(ctx context.Context, userId, q string, now int64, limit int) ([]ChallengeMatch, error) {
+	terms := strings.Fields(strings.ToLower(q))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	challenges, err := s.ChallengeRepo.FindVisible(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var challengeIds = make([]string, 0, len(challenges))
+	for _, challenge := range challenges {
+		challengeIds = append(challengeIds, challenge.ID)
+	}
+	var tags = make(map[string][]string)
+	if len(challengeIds) > 0 {
+		items, err := s.challengeTagRepo.FindNamesByChallengeIdIn(ctx, challengeIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			tags[item.ChallengeId] = append(tags[item.ChallengeId], item.Name)
+		}
+	}
+
+	progress, err := s.Progress(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ChallengeMatch
+	for _, challenge := range challenges {
+		unlocked := !challenge.Conditional() || progress.Unlocked(challenge)
+		score := matchScore(challenge, tags[challenge.ID], terms, unlocked)
+		if score > 0 {
+			matches = append(matches, ChallengeMatch{Challenge: challenge, Tags: tags[challenge.ID], Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Challenge.Sort > matches[j].Challenge.Sort
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// matchScore 名称完全一致 10 分、包含 5 分，标签完全一致 4 分、包含 2 分，类别一致 3 分，描述包含 1 分（withDescription 为 false 时不匹配描述）；
+// 任一关键词未命中时返回 0
+func matchScore(challenge models.Challenge, tags []string, terms []string, withDescription bool) int {
+	name := strings.ToLower(challenge.Name)
+	category := strings.ToLower(challenge.Category)
+	description := strings.ToLower(challenge.Description)
+
+	var total int
+	for _, term := range terms {
+		var score int
+		switch {
+		case name == term:
+			score += 10
+		case strings.Contains(name, term):
+			score += 5
+		}
+		for _, tag := range tags {
+			tag = strings.ToLower(tag)
+			if tag == term {
+				score += 4
+			} else if strings.Contains(tag, term) {
+				score += 2
+			}
+		}
+		if category == term {
+			score += 3
+		}
+		if withDescription && strings.Contains(description, term) {
+			score++
+		}
+		if score == 0 {
+			return 0
+		}
+		total += score
+	}
+	return total
+}
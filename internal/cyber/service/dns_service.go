@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDNSAddr = ":53"
+	defaultDNSTTL  = 30
+	// 权威服务器自身的名称 ns.<gateway.domain>
+	dnsNameServer = "ns"
+)
+
+func NewDNSService(logger *zap.Logger, conf *config.Config, reverseProxyService *ReverseProxyService) *DNSService {
+	return &DNSService{
+		logger:              logger,
+		conf:                conf,
+		reverseProxyService: reverseProxyService,
+	}
+}
+
+// DNSService 内置的权威 DNS，只为启动中或运行中的实例解析 <subdomain>.<gateway.domain>
+type DNSService struct {
+	logger              *zap.Logger
+	conf                *config.Config
+	reverseProxyService *ReverseProxyService
+}
+
+// ListenAndServe 同时监听 UDP 和 TCP
+func (s *DNSService) ListenAndServe() error {
+	addr := s.conf.Gateway.DNS.Addr
+	if addr == "" {
+		addr = defaultDNSAddr
+	}
+	errs := make(chan error, 2)
+	for _, network := range []string{"udp", "tcp"} {
+		server := &dns.Server{Addr: addr, Net: network, Handler: s}
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+	}
+	s.logger.Info("dns server listening at", zap.String("addr", addr), zap.String("zone", s.zone()))
+	return <-errs
+}
+
+func (s *DNSService) zone() string {
+	return dns.Fqdn(strings.ToLower(s.conf.Gateway.Domain))
+}
+
+func (s *DNSService) ttl() uint32 {
+	if s.conf.Gateway.DNS.TTL > 0 {
+		return uint32(s.conf.Gateway.DNS.TTL)
+	}
+	return defaultDNSTTL
+}
+
+func (s *DNSService) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Authoritative = true
+	defer func() {
+		_ = w.WriteMsg(m)
+	}()
+
+	if len(req.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		return
+	}
+	question := req.Question[0]
+	name := strings.ToLower(question.Name)
+	zone := s.zone()
+	if !dns.IsSubDomain(zone, name) {
+		m.Authoritative = false
+		m.Rcode = dns.RcodeRefused
+		return
+	}
+
+	exists, err := s.exists(context.Background(), name)
+	if err != nil {
+		s.logger.Warn("dns find instance", zap.String("name", name), zap.Error(err))
+		m.Rcode = dns.RcodeServerFailure
+		return
+	}
+	if !exists {
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{s.soa()}
+		return
+	}
+
+	switch question.Qtype {
+	case dns.TypeA, dns.TypeANY:
+		if ip := net.ParseIP(s.conf.Gateway.DNS.IPv4).To4(); ip != nil {
+			m.Answer = append(m.Answer, &dns.A{Hdr: s.header(name, dns.TypeA), A: ip})
+		}
+	case dns.TypeAAAA:
+		if ip := net.ParseIP(s.conf.Gateway.DNS.IPv6); ip != nil && ip.To4() == nil {
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip})
+		}
+	case dns.TypeSOA:
+		if name == zone {
+			m.Answer = append(m.Answer, s.soa())
+		}
+	case dns.TypeNS:
+		if name == zone {
+			m.Answer = append(m.Answer, &dns.NS{Hdr: s.header(zone, dns.TypeNS), Ns: dnsNameServer + "." + zone})
+		}
+	}
+	if len(m.Answer) == 0 {
+		// 名称存在但没有该类型的记录
+		m.Ns = []dns.RR{s.soa()}
+	}
+}
+
+// exists 域名本身、权威服务器名称以及存在的实例子域名视为存在
+func (s *DNSService) exists(ctx context.Context, name string) (bool, error) {
+	zone := s.zone()
+	if name == zone {
+		return true, nil
+	}
+	subdomain := strings.TrimSuffix(name, "."+zone)
+	if strings.Contains(subdomain, ".") {
+		return false, nil
+	}
+	if subdomain == dnsNameServer {
+		return true, nil
+	}
+	return s.reverseProxyService.Resolvable(ctx, subdomain)
+}
+
+func (s *DNSService) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl()}
+}
+
+func (s *DNSService) soa() *dns.SOA {
+	zone := s.zone()
+	return &dns.SOA{
+		Hdr:     s.header(zone, dns.TypeSOA),
+		Ns:      dnsNameServer + "." + zone,
+		Mbox:    "hostmaster." + zone,
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  s.ttl(),
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+type dnsRecorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *dnsRecorder) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+func TestDNSServe(t *testing.T) {
+	conf := &config.Config{Gateway: config.Gateway{
+		Domain: "Vuln.Example.com",
+		DNS:    config.GatewayDNS{IPv4: "192.0.2.1", IPv6: "2001:db8::1"},
+	}}
+	routes := &ReverseProxyService{}
+	routes.AddApp("abcd1234", App{Host: "127.0.0.1:32768", Protocol: "http"})
+	s := NewDNSService(zap.NewNop(), conf, routes)
+
+	tests := []struct {
+		name   string
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer string // 期望的应答记录，为空表示没有应答
+	}{
+		{"A", "abcd1234.vuln.example.com.", dns.TypeA, dns.RcodeSuccess, "192.0.2.1"},
+		{"AAAA", "ABCD1234.vuln.example.com.", dns.TypeAAAA, dns.RcodeSuccess, "2001:db8::1"},
+		{"no record of type", "abcd1234.vuln.example.com.", dns.TypeMX, dns.RcodeSuccess, ""},
+		{"unknown name", "missing.vuln.example.com.", dns.TypeA, dns.RcodeNameError, ""},
+		{"nested name", "www.abcd1234.vuln.example.com.", dns.TypeA, dns.RcodeNameError, ""},
+		{"out of zone", "abcd1234.example.org.", dns.TypeA, dns.RcodeRefused, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := new(dns.Msg)
+			req.SetQuestion(tt.qname, tt.qtype)
+			w := &dnsRecorder{}
+			s.ServeDNS(w, req)
+
+			m := w.msg
+			if m.Rcode != tt.rcode {
+				t.Fatalf("rcode = %s, want %s", dns.RcodeToString[m.Rcode], dns.RcodeToString[tt.rcode])
+			}
+			if tt.answer == "" {
+				if len(m.Answer) != 0 {
+					t.Fatalf("answer = %v, want none", m.Answer)
+				}
+				return
+			}
+			if len(m.Answer) != 1 {
+				t.Fatalf("answer = %v, want %s", m.Answer, tt.answer)
+			}
+			var ip net.IP
+			switch rr := m.Answer[0].(type) {
+			case *dns.A:
+				ip = rr.A
+			case *dns.AAAA:
+				ip = rr.AAAA
+			}
+			if !ip.Equal(net.ParseIP(tt.answer)) {
+				t.Fatalf("answer = %v, want %s", m.Answer[0], tt.answer)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/flagmatch"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FlagService 题目的分阶段 Flag
+type FlagService struct {
+	*orz.Service
+	*repo.ChallengeFlagRepo
+	flagCaptureRepo *repo.FlagCaptureRepo
+}
+
+func NewFlagService(db *gorm.DB) *FlagService {
+	return &FlagService{
+		Service:           orz.NewService(db),
+		ChallengeFlagRepo: repo.NewChallengeFlagRepo(db),
+		flagCaptureRepo:   repo.NewFlagCaptureRepo(db),
+	}
+}
+
+// EnvName 动态Flag注入容器的环境变量名
+func EnvName(stage models.ChallengeFlag) string {
+	if stage.Env != "" {
+		return stage.Env
+	}
+	var b strings.Builder
+	b.WriteString("FLAG_")
+	for _, r := range strings.ToUpper(stage.Name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// Generate 为每个动态阶段生成 Flag，返回 Flag 和需要注入容器的环境变量
+func (s *FlagService) Generate(stages []models.ChallengeFlag) (models.FlagValues, []string) {
+	var (
+		values = make(models.FlagValues)
+		env    []string
+	)
+	for _, stage := range stages {
+		if !stage.Dynamic {
+			continue
+		}
+		value := fmt.Sprintf(`cyberpoc-{%s}`, uuid.NewString())
+		values[stage.ID] = value
+		env = append(env, EnvName(stage)+"="+value)
+	}
+	return values, env
+}
+
+// Match 查找提交的 Flag 对应的阶段
+func (s *FlagService) Match(instance models.Instance, stages []models.ChallengeFlag, flag string) *models.ChallengeFlag {
+	for _, stage := range stages {
+		expected := stage.Flag
+		if stage.Dynamic {
+			expected = instance.StageFlags[stage.ID]
+		}
+		if flagmatch.Match(stage.Match, expected, flag, stage.StripWrapper) {
+			return &stage
+		}
+	}
+	return nil
+}
+
+// Capture 记录用户提交的阶段，重复提交不会重复计分
+func (s *FlagService) Capture(ctx context.Context, userId string, stage models.ChallengeFlag) error {
+	items, err := s.flagCaptureRepo.FindByChallengeIdAndUserId(ctx, stage.ChallengeId, userId)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.FlagId == stage.ID {
+			return nil
+		}
+	}
+	return s.flagCaptureRepo.Create(ctx, &models.FlagCapture{
+		ID:          uuid.NewString(),
+		UserId:      userId,
+		FlagId:      stage.ID,
+		ChallengeId: stage.ChallengeId,
+		Points:      stage.Points,
+		CapturedAt:  time.Now().UnixMilli(),
+	})
+}
+
+// Progress 用户已提交的阶段，以及是否已提交全部必需阶段
+func (s *FlagService) Progress(ctx context.Context, userId, challengeId string, stages []models.ChallengeFlag) (captured []string, completed bool, err error) {
+	items, err := s.flagCaptureRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	var capturedIds = make(map[string]bool, len(items))
+	for _, item := range items {
+		capturedIds[item.FlagId] = true
+	}
+	completed = true
+	for _, stage := range stages {
+		if capturedIds[stage.ID] {
+			captured = append(captured, stage.Name)
+		} else if !stage.Optional {
+			completed = false
+		}
+	}
+	return captured, completed, nil
+}
+
+// StageViews 玩家看到的阶段，不包含 Flag
+func (s *FlagService) StageViews(ctx context.Context, challengeId, userId string) ([]views.FlagStageView, error) {
+	stages, err := s.ChallengeFlagRepo.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return nil, err
+	}
+	var capturedIds = make(map[string]bool)
+	if userId != "" {
+		items, err := s.flagCaptureRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			capturedIds[item.FlagId] = true
+		}
+	}
+	var items = make([]views.FlagStageView, 0, len(stages))
+	for _, stage := range stages {
+		items = append(items, views.FlagStageView{
+			ID:       stage.ID,
+			Name:     stage.Name,
+			Points:   stage.Points,
+			Optional: stage.Optional,
+			Captured: capturedIds[stage.ID],
+		})
+	}
+	return items, nil
+}
+
+// CheckStage 校验阶段配置
+func (s *FlagService) CheckStage(stage models.ChallengeFlag) error {
+	if strings.TrimSpace(stage.Name) == "" || stage.Points < 0 {
+		return xe.ErrInvalidFlagStage
+	}
+	if !stage.Dynamic && stage.Flag == "" {
+		return xe.ErrInvalidFlagStage
+	}
+	if stage.Env != "" && !envNamePattern.MatchString(stage.Env) {
+		return xe.ErrInvalidFlagStage
+	}
+	return checkFlagMatch(stage.Match, stage.Flag, stage.Dynamic)
+}
+
+// checkFlagMatch 校验 Flag 匹配方式，动态Flag是生成的固定值，不能使用正则匹配
+func checkFlagMatch(mode, flag string, dynamic bool) error {
+	if dynamic && mode == flagmatch.Regex {
+		return xe.ErrInvalidFlagMatch
+	}
+	if err := flagmatch.Validate(mode, flag); err != nil {
+		return xe.ErrInvalidFlagMatch
+	}
+	return nil
+}
+
+// ReplaceByChallengeId 按名称覆盖题目的阶段，保留同名阶段的ID以免提交记录失效
+func (s *FlagService) ReplaceByChallengeId(ctx context.Context, challengeId string, stages []models.ChallengeFlag) error {
+	existing, err := s.ChallengeFlagRepo.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	var byName = make(map[string]models.ChallengeFlag, len(existing))
+	for _, stage := range existing {
+		byName[stage.Name] = stage
+	}
+	for i, stage := range stages {
+		stage.ChallengeId = challengeId
+		stage.Sort = int64(i)
+		if old, ok := byName[stage.Name]; ok {
+			stage.ID = old.ID
+			stage.CreatedAt = old.CreatedAt
+			delete(byName, stage.Name)
+			err = s.ChallengeFlagRepo.Save(ctx, &stage)
+		} else {
+			stage.ID = uuid.NewString()
+			err = s.ChallengeFlagRepo.Create(ctx, &stage)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, stage := range byName {
+		if err := s.ChallengeFlagRepo.DeleteById(ctx, stage.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SumPointsGroupByUserId 每个用户提交阶段 Flag 获得的总分
+func (s *FlagService) SumPointsGroupByUserId(ctx context.Context) (map[string]int64, error) {
+	return s.flagCaptureRepo.SumPointsGroupByUserId(ctx)
+}
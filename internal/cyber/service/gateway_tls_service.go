@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/pkg/certs"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	defaultLocalCADir = "data/ca"
+	defaultACMEDir    = "data/acme"
+)
+
+func NewGatewayTLSService(conf *config.Config, reverseProxyService *ReverseProxyService) *GatewayTLSService {
+	return &GatewayTLSService{
+		conf:                conf,
+		reverseProxyService: reverseProxyService,
+	}
+}
+
+// GatewayTLSService 为统一网关提供证书
+type GatewayTLSService struct {
+	conf                *config.Config
+	reverseProxyService *ReverseProxyService
+
+	mu      sync.Mutex
+	localCA *certs.LocalCA
+}
+
+// TLSConfig 根据配置的证书来源构建网关的 TLS 配置
+func (s *GatewayTLSService) TLSConfig() (*tls.Config, error) {
+	conf := s.conf.Gateway.TLS
+	switch conf.Provider {
+	case config.TLSProviderFile:
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载网关证书失败: %w", err)
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	case config.TLSProviderLocalCA:
+		ca, err := s.LocalCA()
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				host := strings.ToLower(hello.ServerName)
+				// 与 ACME 相同，只为存在的实例签发证书，避免任意域名的握手消耗 CPU 和内存
+				if err := s.hostPolicy(hello.Context(), host); err != nil {
+					return nil, err
+				}
+				return ca.Certificate(host)
+			},
+		}, nil
+	case config.TLSProviderACME:
+		cacheDir := conf.CacheDir
+		if cacheDir == "" {
+			cacheDir = defaultACMEDir
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cacheDir),
+			HostPolicy: s.hostPolicy,
+			Email:      conf.Email,
+		}
+		if conf.CAServer != "" {
+			manager.Client = &acme.Client{DirectoryURL: conf.CAServer}
+		}
+		return manager.TLSConfig(), nil
+	default:
+		return nil, fmt.Errorf("不支持的网关证书来源: %s", conf.Provider)
+	}
+}
+
+// LocalCA 加载本地 CA，首次使用时生成
+func (s *GatewayTLSService) LocalCA() (*certs.LocalCA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.localCA != nil {
+		return s.localCA, nil
+	}
+	dir := s.conf.Gateway.TLS.CADir
+	if dir == "" {
+		dir = defaultLocalCADir
+	}
+	ca, err := certs.LoadOrCreateLocalCA(dir)
+	if err != nil {
+		return nil, fmt.Errorf("加载本地 CA 失败: %w", err)
+	}
+	s.localCA = ca
+	return ca, nil
+}
+
+// hostPolicy 只为存在的实例签发或申请证书，避免被任意域名消耗资源和签发额度
+func (s *GatewayTLSService) hostPolicy(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	if s.pathModeHost(host) {
+		return nil
+	}
+	subdomain, ok := s.subdomain(host)
+	if !ok {
+		return fmt.Errorf("unknown host: %s", host)
+	}
+	// 启动中的实例也需要证书，才能展示等待页面
+	exists, err := s.reverseProxyService.Resolvable(ctx, subdomain)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("instance not found: %s", host)
+	}
+	return nil
+}
+
+// domainHost gateway.domain 去掉端口后的主机名
+func (s *GatewayTLSService) domainHost() string {
+	domain := strings.ToLower(s.conf.Gateway.Domain)
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		return host
+	}
+	return domain
+}
+
+// pathModeHost 按路径路由时所有实例都通过 gateway.domain 访问
+func (s *GatewayTLSService) pathModeHost(host string) bool {
+	return s.conf.Gateway.PathMode() && host == s.domainHost()
+}
+
+// subdomain 解析 <subdomain>.<gateway.domain> 形式的主机名
+func (s *GatewayTLSService) subdomain(host string) (string, bool) {
+	suffix := "." + s.domainHost()
+	subdomain, ok := strings.CutSuffix(host, suffix)
+	if !ok || subdomain == "" || strings.Contains(subdomain, ".") {
+		return "", false
+	}
+	return subdomain, true
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/tools"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// checkerTargetHost 检查器访问临时环境使用的主机名
+	checkerTargetHost = "target"
+	// maxCheckOutput 保存的检查器输出只保留末尾部分
+	maxCheckOutput = 16 * 1024
+	// healthCheckRetention 检查记录的保留时间
+	healthCheckRetention = 30 * 24 * time.Hour
+)
+
+// HealthCheckService 题目健康检查：启动临时环境，在同一网络中运行检查器，检查器退出码为0时通过
+type HealthCheckService struct {
+	*orz.Service
+	*repo.HealthCheckRepo
+	logger        *zap.Logger
+	conf          *config.Config
+	challengeRepo *repo.ChallengeRepo
+	imageService  *ImageService
+	flagService   *FlagService
+
+	running sync.Map    // 检查中的题目 challengeId -> bool
+	round   atomic.Bool // 定时检查是否在进行中
+}
+
+func NewHealthCheckService(db *gorm.DB, logger *zap.Logger, conf *config.Config, imageService *ImageService, flagService *FlagService) *HealthCheckService {
+	return &HealthCheckService{
+		Service:         orz.NewService(db),
+		HealthCheckRepo: repo.NewHealthCheckRepo(db),
+		logger:          logger,
+		conf:            conf,
+		challengeRepo:   repo.NewChallengeRepo(db),
+		imageService:    imageService,
+		flagService:     flagService,
+	}
+}
+
+// Start 在后台检查一个题目
+func (s *HealthCheckService) Start(ctx context.Context, challengeId string) error {
+	challenge, exists, err := s.challengeRepo.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrChallengeNotFound
+	}
+	if !challenge.HasChecker() || !challenge.RequiresInstance() {
+		return xe.ErrNoChecker
+	}
+	if _, running := s.running.Load(challengeId); running {
+		return xe.ErrHealthCheckRunning
+	}
+	go func() {
+		if _, err := s.Check(context.Background(), challengeId); err != nil {
+			s.logger.Warn("health check failed to run", zap.String("challenge", challengeId), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// CheckAll 依次检查全部启用且配置了检查器的题目，上一轮未结束时跳过
+func (s *HealthCheckService) CheckAll(ctx context.Context) error {
+	if !s.round.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer s.round.Store(false)
+
+	if err := s.HealthCheckRepo.DeleteBefore(ctx, time.Now().Add(-healthCheckRetention).UnixMilli()); err != nil {
+		return err
+	}
+	challenges, err := s.challengeRepo.FindEnabledWithChecker(ctx)
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		_, err := s.Check(ctx, challenge.ID)
+		if err != nil && !errors.Is(err, xe.ErrHealthCheckRunning) {
+			s.logger.Warn("health check failed to run", zap.String("challenge", challenge.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Check 检查一个题目并保存结果，环境或检查器未能运行也记为未通过
+func (s *HealthCheckService) Check(ctx context.Context, challengeId string) (models.HealthCheck, error) {
+	if _, running := s.running.LoadOrStore(challengeId, true); running {
+		return models.HealthCheck{}, xe.ErrHealthCheckRunning
+	}
+	defer s.running.Delete(challengeId)
+
+	challenge, exists, err := s.challengeRepo.FindByIdExists(ctx, challengeId)
+	if err != nil {
+		return models.HealthCheck{}, err
+	}
+	if !exists {
+		return models.HealthCheck{}, xe.ErrChallengeNotFound
+	}
+	if !challenge.HasChecker() || !challenge.RequiresInstance() {
+		return models.HealthCheck{}, xe.ErrNoChecker
+	}
+	image, exists, err := s.imageService.FindByIdExists(ctx, challenge.ImageId)
+	if err != nil {
+		return models.HealthCheck{}, err
+	}
+	if !exists {
+		return models.HealthCheck{}, xe.ErrImageNotFound
+	}
+
+	start := time.Now()
+	exitCode, output, err := s.run(ctx, challenge, image)
+	item := models.HealthCheck{
+		ID:          uuid.NewString(),
+		ChallengeId: challengeId,
+		Passed:      err == nil && exitCode == 0,
+		ExitCode:    exitCode,
+		Output:      tail(output, maxCheckOutput),
+		Duration:    time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		item.Error = err.Error()
+	}
+	if !item.Passed {
+		s.logger.Warn("challenge unhealthy", zap.String("challenge", challengeId), zap.Int("exit_code", exitCode), zap.String("error", item.Error))
+	}
+	return item, s.HealthCheckRepo.Create(ctx, &item)
+}
+
+// run 在独立的网络中启动临时环境和检查器，返回检查器的退出码和输出，结束后清理容器和网络
+func (s *HealthCheckService) run(ctx context.Context, challenge models.Challenge, image models.Image) (int, string, error) {
+	timeout := s.conf.Health.Timeout
+	if timeout <= 0 {
+		timeout = 120
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	checkerImage := challenge.CheckerImage
+	if checkerImage == "" {
+		checkerImage = s.conf.Health.DefaultImage
+	}
+	if checkerImage == "" {
+		checkerImage = "python:3-alpine"
+	}
+	cli := s.imageService.DockerClient()
+	if _, err := cli.ImageInspect(ctx, checkerImage); err != nil {
+		rc, err := cli.ImagePull(ctx, checkerImage, imagetypes.PullOptions{})
+		if err != nil {
+			return -1, "", fmt.Errorf("pull checker image: %w", err)
+		}
+		_, _ = io.Copy(io.Discard, rc)
+		rc.Close()
+	}
+
+	flag := challenge.Flag
+	if challenge.DynamicFlag {
+		flag = fmt.Sprintf(`cyberpoc-{%s}`, uuid.NewString())
+	}
+	stages, err := s.flagService.FindByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return -1, "", err
+	}
+	_, stageEnv := s.flagService.Generate(stages)
+
+	name := "cyberpoc-check-" + strings.ToLower(tools.RandomId(12))
+	targetName, checkerName := name+"-target", name+"-checker"
+	if _, err := cli.NetworkCreate(ctx, name, network.CreateOptions{}); err != nil {
+		return -1, "", fmt.Errorf("create network: %w", err)
+	}
+	defer func() {
+		background := context.Background()
+		for _, id := range []string{checkerName, targetName} {
+			_ = cli.ContainerRemove(background, id, container.RemoveOptions{Force: true})
+		}
+		if err := cli.NetworkRemove(background, name); err != nil {
+			s.logger.Warn("remove health check network", zap.String("network", name), zap.Error(err))
+		}
+	}()
+
+	_, err = cli.ContainerCreate(ctx, &container.Config{
+		Env:   append([]string{"flag=" + flag}, stageEnv...),
+		Image: image.Registry,
+	}, &container.HostConfig{
+		Resources: container.Resources{
+			Memory:   image.MemoryLimit * 1024 * 1024,
+			NanoCPUs: int64(1000000000 * image.CpuLimit),
+		},
+	}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			name: {Aliases: []string{checkerTargetHost}},
+		},
+	}, nil, targetName)
+	if err != nil {
+		return -1, "", fmt.Errorf("create target: %w", err)
+	}
+	if err := cli.ContainerStart(ctx, targetName, container.StartOptions{}); err != nil {
+		return -1, "", fmt.Errorf("start target: %w", err)
+	}
+
+	port := strings.TrimSpace(strings.Split(strings.Split(image.Exposed, ",")[0], "/")[0])
+	checkerConfig := &container.Config{
+		Env: append([]string{
+			"TARGET_HOST=" + checkerTargetHost,
+			"TARGET_PORT=" + port,
+			"TARGET_URL=" + fmt.Sprintf("http://%s:%s", checkerTargetHost, port),
+			"FLAG=" + flag,
+		}, stageEnv...),
+		Image: checkerImage,
+	}
+	if challenge.CheckerScript != "" {
+		checkerConfig.Entrypoint = []string{"sh", "-c"}
+		checkerConfig.Cmd = []string{challenge.CheckerScript}
+	}
+	_, err = cli.ContainerCreate(ctx, checkerConfig, &container.HostConfig{}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{name: {}},
+	}, nil, checkerName)
+	if err != nil {
+		return -1, "", fmt.Errorf("create checker: %w", err)
+	}
+	if err := cli.ContainerStart(ctx, checkerName, container.StartOptions{}); err != nil {
+		return -1, "", fmt.Errorf("start checker: %w", err)
+	}
+
+	exitCode := -1
+	statusCh, errCh := cli.ContainerWait(ctx, checkerName, container.WaitConditionNotRunning)
+	select {
+	case status := <-statusCh:
+		exitCode = int(status.StatusCode)
+		if status.Error != nil {
+			err = errors.New(status.Error.Message)
+		}
+	case err = <-errCh:
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("检查超时（%d秒）", timeout)
+	}
+
+	var output bytes.Buffer
+	if rc, logErr := cli.ContainerLogs(context.Background(), checkerName, container.LogsOptions{ShowStdout: true, ShowStderr: true}); logErr == nil {
+		_, _ = stdcopy.StdCopy(&output, &output, rc)
+		rc.Close()
+	}
+	return exitCode, output.String(), err
+}
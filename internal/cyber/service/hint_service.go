@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type HintService struct {
+	*orz.Service
+	*repo.ChallengeHintRepo
+	hintUnlockRepo *repo.HintUnlockRepo
+}
+
+func NewHintService(db *gorm.DB) *HintService {
+	return &HintService{
+		Service:           orz.NewService(db),
+		ChallengeHintRepo: repo.NewChallengeHintRepo(db),
+		hintUnlockRepo:    repo.NewHintUnlockRepo(db),
+	}
+}
+
+// Unlock 解锁提示并返回提示内容，重复解锁不会重复扣分
+func (s *HintService) Unlock(ctx context.Context, userId, challengeId, hintId string) (*models.ChallengeHint, error) {
+	hint, exists, err := s.ChallengeHintRepo.FindByIdExists(ctx, hintId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || hint.ChallengeId != challengeId {
+		return nil, xe.ErrHintNotFound
+	}
+	if hint.Cost == 0 {
+		return &hint, nil
+	}
+
+	err = s.Transaction(ctx, func(ctx context.Context) error {
+		unlocked, err := s.hintUnlockRepo.ExistsByHintIdAndUserId(ctx, hintId, userId)
+		if err != nil {
+			return err
+		}
+		if unlocked {
+			return nil
+		}
+		return s.hintUnlockRepo.Create(ctx, &models.HintUnlock{
+			ID:          uuid.NewString(),
+			UserId:      userId,
+			HintId:      hintId,
+			ChallengeId: challengeId,
+			Cost:        hint.Cost,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hint, nil
+}
+
+// Views 玩家看到的提示，免费或已解锁的提示包含内容，未解锁的只展示分数
+func (s *HintService) Views(ctx context.Context, challengeId, userId string) ([]views.HintView, error) {
+	hints, err := s.ChallengeHintRepo.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return nil, err
+	}
+	var unlocked = make(map[string]bool)
+	if userId != "" {
+		unlocks, err := s.hintUnlockRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, unlock := range unlocks {
+			unlocked[unlock.HintId] = true
+		}
+	}
+
+	var items = make([]views.HintView, 0, len(hints))
+	for _, hint := range hints {
+		item := views.HintView{
+			ID:       hint.ID,
+			Cost:     hint.Cost,
+			Unlocked: hint.Cost == 0 || unlocked[hint.ID],
+		}
+		if item.Unlocked {
+			item.Content = hint.Content
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ReplaceByChallengeId 覆盖题目的提示，内容相同的提示保留原有ID以免解锁记录失效，
+// 内容变化的提示使用新的ID，已解锁旧提示的用户需要重新解锁
+func (s *HintService) ReplaceByChallengeId(ctx context.Context, challengeId string, hints []models.ChallengeHint) error {
+	existing, err := s.ChallengeHintRepo.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	var byContent = make(map[string][]models.ChallengeHint, len(existing))
+	for _, hint := range existing {
+		byContent[hint.Content] = append(byContent[hint.Content], hint)
+	}
+	for i, hint := range hints {
+		hint.ChallengeId = challengeId
+		hint.Sort = int64(i)
+		if olds := byContent[hint.Content]; len(olds) > 0 {
+			hint.ID = olds[0].ID
+			hint.CreatedAt = olds[0].CreatedAt
+			byContent[hint.Content] = olds[1:]
+			err = s.ChallengeHintRepo.Save(ctx, &hint)
+		} else {
+			hint.ID = uuid.NewString()
+			err = s.ChallengeHintRepo.Create(ctx, &hint)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, olds := range byContent {
+		for _, hint := range olds {
+			if err := s.ChallengeHintRepo.DeleteById(ctx, hint.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteByChallengeId 删除题目的全部提示及解锁记录，删除后不再扣除解锁提示的分数
+func (s *HintService) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	if err := s.ReplaceByChallengeId(ctx, challengeId, nil); err != nil {
+		return err
+	}
+	return s.hintUnlockRepo.DeleteByChallengeId(ctx, challengeId)
+}
+
+// SumCostGroupByUserId 每个用户解锁提示扣除的总分
+func (s *HintService) SumCostGroupByUserId(ctx context.Context) (map[string]int64, error) {
+	return s.hintUnlockRepo.SumCostGroupByUserId(ctx)
+}
@@ -2,24 +2,43 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/docker/docker/api/types/build"
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
+	"github.com/dushixiang/cyberpoc/internal/config"
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/tools"
 	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// maxBuildLogSize 保存到数据库的构建日志只保留末尾部分
+const maxBuildLogSize = 64 * 1024
+
 type ImageService struct {
 	*orz.Service
 	*repo.ImageRepo
 	client *client.Client
+	conf   *config.Config
+	logger *zap.Logger
+
+	builds sync.Map // 进行中的构建 imageId -> *BuildLog
 }
 
-func NewImageService(db *gorm.DB) *ImageService {
+func NewImageService(db *gorm.DB, conf *config.Config, logger *zap.Logger) *ImageService {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		panic(err)
@@ -28,6 +47,8 @@ func NewImageService(db *gorm.DB) *ImageService {
 		Service:   orz.NewService(db),
 		ImageRepo: repo.NewImageRepo(db),
 		client:    cli,
+		conf:      conf,
+		logger:    logger,
 	}
 }
 
@@ -56,6 +77,9 @@ func (s *ImageService) Pull(ctx context.Context, id string) error {
 	if !exists {
 		return xe.ErrImageNotFound
 	}
+	if img.Built() {
+		return xe.ErrImageNotPullable
+	}
 
 	_ = s.UpdateStatus(ctx, id, models.ImageStatusPulling)
 
@@ -148,9 +172,223 @@ func (s *ImageService) PullAll(ctx context.Context) error {
 	go func() {
 		background := context.Background()
 		for _, it := range items {
+			if it.Built() {
+				continue
+			}
 			// 忽略单个错误，继续其他镜像
 			_ = s.Pull(background, it.ID)
 		}
 	}()
 	return nil
 }
+
+// Build 从构建上下文构建镜像，构建上下文为 tar 或压缩的 tar，镜像名使用 Registry。
+// 构建在后台执行，日志通过 BuildLog 跟随，结束后保存到数据库
+func (s *ImageService) Build(ctx context.Context, id string, buildContext io.ReadCloser) error {
+	img, exists, err := s.ImageRepo.FindByIdExists(ctx, id)
+	if err != nil {
+		buildContext.Close()
+		return err
+	}
+	if !exists {
+		buildContext.Close()
+		return xe.ErrImageNotFound
+	}
+	log := newBuildLog()
+	if _, running := s.builds.LoadOrStore(id, log); running {
+		buildContext.Close()
+		return xe.ErrImageBuilding
+	}
+
+	err = s.ImageRepo.UpdateColumnsById(ctx, id, orz.Map{
+		"source": models.ImageSourceBuild,
+		"status": models.ImageStatusBuilding,
+	})
+	if err != nil {
+		s.builds.Delete(id)
+		buildContext.Close()
+		return err
+	}
+
+	go func() {
+		defer buildContext.Close()
+		background := context.Background()
+		status := models.ImageStatusReady
+		if err := s.build(background, img, buildContext, log); err != nil {
+			log.append("ERROR: " + err.Error())
+			s.logger.Warn("build image failed", zap.String("id", id), zap.Error(err))
+			status = models.ImageStatusBuildFailed
+		}
+
+		columns := orz.Map{
+			"status":    status,
+			"build_log": tail(log.String(), maxBuildLogSize),
+		}
+		if status == models.ImageStatusReady {
+			columns["built_at"] = time.Now().UnixMilli()
+		}
+		if err := s.ImageRepo.UpdateColumnsById(background, id, columns); err != nil {
+			s.logger.Warn("save build result failed", zap.String("id", id), zap.Error(err))
+		}
+		s.builds.Delete(id)
+		log.finish()
+	}()
+	return nil
+}
+
+// FailInterruptedBuilds 构建在后台执行，服务重启后仍为构建中的镜像已无法完成，启动时标记为构建失败
+func (s *ImageService) FailInterruptedBuilds(ctx context.Context) error {
+	return s.ImageRepo.UpdateColumnsByStatus(ctx, models.ImageStatusBuilding, orz.Map{
+		"status":    models.ImageStatusBuildFailed,
+		"build_log": "ERROR: 构建因服务重启而中断，请重新构建",
+	})
+}
+
+// BuildUpload 使用上传的构建上下文构建镜像，支持 tar、tar.gz 和 zip。
+// 上传的文件在请求结束后会被删除，因此先复制到临时文件，构建结束后再删除
+func (s *ImageService) BuildUpload(ctx context.Context, id, filename string, upload io.Reader) error {
+	name := strings.ToLower(filename)
+	isZip := strings.HasSuffix(name, ".zip")
+	if !isZip && !strings.HasSuffix(name, ".tar") && !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+		return xe.ErrInvalidBuildContext
+	}
+
+	f, err := os.CreateTemp("", "cyberpoc-build-*")
+	if err != nil {
+		return err
+	}
+	tmp := tempFile{File: f}
+	size, err := io.Copy(f, upload)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if !isZip {
+		return s.Build(ctx, id, tmp)
+	}
+
+	// Docker 不接受 zip，转换为 tar
+	pr, pw := io.Pipe()
+	go func() {
+		defer tmp.Close()
+		pw.CloseWithError(tools.ZipToTar(f, size, pw))
+	}()
+	return s.Build(ctx, id, pr)
+}
+
+// tempFile 关闭时删除的临时文件
+type tempFile struct {
+	*os.File
+}
+
+func (f tempFile) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
+
+// BuildFromPath 从构建根目录下的目录构建镜像，p 为相对于构建根目录的路径，为空时使用上次构建的目录
+func (s *ImageService) BuildFromPath(ctx context.Context, id, p string) error {
+	root := s.conf.Image.BuildRoot
+	if root == "" {
+		return xe.ErrImageBuildDisabled
+	}
+	if p == "" {
+		img, exists, err := s.ImageRepo.FindByIdExists(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return xe.ErrImageNotFound
+		}
+		p = img.BuildPath
+	}
+	// 限制在根目录内
+	dir := filepath.Join(root, filepath.Clean("/"+p))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return xe.ErrInvalidBuildContext
+	}
+	if err := s.ImageRepo.UpdateColumnsById(ctx, id, orz.Map{"build_path": p}); err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tools.TarDir(dir, pw))
+	}()
+	return s.Build(ctx, id, pr)
+}
+
+// BuildLog 进行中的构建日志，没有进行中的构建时返回 false
+func (s *ImageService) BuildLog(id string) (*BuildLog, bool) {
+	log, ok := s.builds.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return log.(*BuildLog), true
+}
+
+// buildMessage Docker 构建接口返回的 JSON 消息
+type buildMessage struct {
+	Stream      string `json:"stream"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+func (s *ImageService) build(ctx context.Context, img models.Image, buildContext io.Reader, log *BuildLog) error {
+	resp, err := s.DockerClient().ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        []string{img.Registry},
+		Dockerfile:  img.Dockerfile,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message buildMessage
+		if err := decoder.Decode(&message); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+		text := message.Stream
+		if text == "" {
+			text = message.Status
+		}
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			if line != "" {
+				log.append(line)
+			}
+		}
+	}
+
+	// 校验是否已在本地
+	_, err = s.DockerClient().ImageInspect(ctx, img.Registry)
+	return err
+}
+
+// tail 只保留字符串末尾的约 n 个字节，不截断 UTF-8 字符
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return s[i:]
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/dushixiang/cyberpoc/internal/config"
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
 	"github.com/dushixiang/cyberpoc/internal/identity"
+	"github.com/dushixiang/cyberpoc/pkg/flagmatch"
 	"github.com/dushixiang/cyberpoc/pkg/tools"
 	"github.com/dushixiang/cyberpoc/pkg/xe"
 
@@ -38,13 +40,14 @@ type InstanceService struct {
 	imageService           *ImageService
 	solveService           *SolveService
 	reverseProxyService    *ReverseProxyService
+	flagService            *FlagService
 	client                 *client.Client
 
 	timer cache.Cache[string, bool]
 }
 
 func NewInstanceService(db *gorm.DB, logger *zap.Logger, conf *config.Config, challengeService *ChallengeService, challengeRecordService *ChallengeRecordService, imageService *ImageService,
-	solveService *SolveService, reverseProxyService *ReverseProxyService) *InstanceService {
+	solveService *SolveService, reverseProxyService *ReverseProxyService, flagService *FlagService) *InstanceService {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		panic(fmt.Errorf("初始化Docker客户端失败: %v", err))
@@ -60,6 +63,7 @@ func NewInstanceService(db *gorm.DB, logger *zap.Logger, conf *config.Config, ch
 		imageService:           imageService,
 		solveService:           solveService,
 		reverseProxyService:    reverseProxyService,
+		flagService:            flagService,
 		client:                 cli,
 	}
 	timer := cache.New[string, bool](time.Minute, cache.Option[string, bool]{
@@ -104,9 +108,15 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 	if err != nil {
 		return err
 	}
-	if !exists {
+	if !exists || !challenge.Visible(time.Now().UnixMilli()) {
 		return xe.ErrChallengeNotFound
 	}
+	if !challenge.RequiresInstance() {
+		return xe.ErrNoInstanceRequired
+	}
+	if err := s.challengeService.CheckUnlocked(ctx, userId, challenge); err != nil {
+		return err
+	}
 	image, exists, err := s.imageService.FindByIdExists(ctx, challenge.ImageId)
 	if err != nil {
 		return err
@@ -130,6 +140,13 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 		flag = fmt.Sprintf(`cyberpoc-{%s}`, uuid.NewString())
 	}
 
+	// 分阶段的动态Flag分别注入
+	stages, err := s.flagService.FindByChallengeId(ctx, challengeId)
+	if err != nil {
+		return err
+	}
+	stageFlags, stageEnv := s.flagService.Generate(stages)
+
 	var (
 		subdomain string
 		accessUrl string
@@ -140,12 +157,7 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 			return err
 		}
 		subdomain = strings.ToLower(subdomain)
-		domain := s.conf.Gateway.Domain
-		if s.conf.Gateway.Https {
-			accessUrl = fmt.Sprintf(`https://%s.%s`, subdomain, domain)
-		} else {
-			accessUrl = fmt.Sprintf(`http://%s.%s`, subdomain, domain)
-		}
+		accessUrl = s.conf.Gateway.AccessUrl(subdomain)
 	}
 
 	instance := models.Instance{
@@ -155,7 +167,9 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 		ChallengeId:   challengeId,
 		ChallengeName: challengeName,
 		Flag:          flag,
+		StageFlags:    stageFlags,
 		Exposed:       image.Exposed,
+		Protocol:      image.Protocol,
 		Duration:      challenge.Duration,
 		CpuLimit:      image.CpuLimit,
 		MemoryLimit:   image.MemoryLimit,
@@ -171,7 +185,7 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 	cli := s.DockerClient()
 
 	cc := container.Config{
-		Env:   []string{"flag=" + flag},
+		Env:   append([]string{"flag=" + flag}, stageEnv...),
 		Image: image.Registry,
 	}
 
@@ -219,6 +233,7 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 		ChallengeId:   challengeId,
 		ChallengeName: challengeName,
 		InstanceId:    instance.ID,
+		Subdomain:     subdomain,
 		CreatedAt:     time.Now().UnixMilli(),
 	}
 	_ = s.challengeRecordService.Create(ctx, &challengeRecord)
@@ -235,6 +250,22 @@ func (s *InstanceService) Run(ctx context.Context, userId, challengeId string) e
 	return nil
 }
 
+// subdomainLength 随机生成的实例子域名长度
+const subdomainLength = 8
+
+// isInstanceSubdomain 是否符合随机生成的实例子域名格式（小写字母和数字）
+func isInstanceSubdomain(subdomain string) bool {
+	if len(subdomain) != subdomainLength {
+		return false
+	}
+	for _, c := range subdomain {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *InstanceService) GenerateRandomSubdomain(ctx context.Context) (string, error) {
 	instances, err := s.InstanceRepo.FindAll(ctx)
 	if err != nil {
@@ -250,7 +281,7 @@ func (s *InstanceService) GenerateRandomSubdomain(ctx context.Context) (string,
 		case <-ctx.Done():
 			return "", fmt.Errorf("生成随机子域名超时")
 		default:
-			subdomain := tools.RandomId(8)
+			subdomain := tools.RandomId(subdomainLength)
 			if !usedSubdomains[subdomain] {
 				return subdomain, nil
 			}
@@ -307,13 +338,16 @@ func (s *InstanceService) Destroy(ctx context.Context, id string) error {
 }
 
 func (s *InstanceService) destroy(ctx context.Context, id string) error {
-	exists, err := s.InstanceRepo.ExistsById(ctx, id)
+	instance, exists, err := s.InstanceRepo.FindByIdExists(ctx, id)
 	if err != nil {
 		return err
 	}
 	if !exists {
 		return nil
 	}
+	if instance.Subdomain != "" {
+		s.reverseProxyService.DelApp(instance.Subdomain)
+	}
 	cli := s.DockerClient()
 	s.logger.Debug("container destroy", zap.String("id", id))
 	err = cli.ContainerRemove(ctx, id, container.RemoveOptions{
@@ -327,41 +361,119 @@ func (s *InstanceService) destroy(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *InstanceService) SubmitFlag(ctx context.Context, id, flag string) (bool, error) {
+// SubmitFlag 提交 Flag，题目配置了阶段时按阶段计分，提交全部必需阶段后才算通关
+func (s *InstanceService) SubmitFlag(ctx context.Context, id, flag string) (*views.FlagResult, error) {
 	s.Lock()
 	defer s.Unlock()
 
 	exists, err := s.InstanceRepo.ExistsById(ctx, id)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	if !exists {
-		return false, nil
+		return &views.FlagResult{}, nil
 	}
 
 	instance, exists, err := s.InstanceRepo.FindByIdExists(ctx, id)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	if !exists {
-		return false, xe.ErrInstanceNotFound
+		return nil, xe.ErrInstanceNotFound
+	}
+
+	result, err := s.submit(ctx, instance, flag)
+	if err != nil {
+		return nil, err
+	}
+	if result.Solved {
+		// 销毁环境
+		_ = s.Destroy(ctx, id)
+	}
+	return result, nil
+}
+
+// SubmitStaticFlag 无需启动环境的题目直接提交 Flag，以用户第一次查看题目的时间作为开始时间
+func (s *InstanceService) SubmitStaticFlag(ctx context.Context, userId string, challenge models.Challenge, flag string) (*views.FlagResult, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	startAt := time.Now().UnixMilli()
+	record, exists, err := s.challengeRecordService.FindFirstByUserIdAndChallengeId(ctx, userId, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		startAt = record.CreatedAt
+	} else if err := s.challengeRecordService.RecordStart(ctx, userId, challenge); err != nil {
+		return nil, err
+	}
+
+	instance := models.Instance{
+		UserId:      userId,
+		ChallengeId: challenge.ID,
+		Flag:        challenge.Flag,
+		CreatedAt:   startAt,
+	}
+	return s.submit(ctx, instance, flag)
+}
+
+// submit 校验 Flag 并记录阶段与通关
+func (s *InstanceService) submit(ctx context.Context, instance models.Instance, flag string) (*views.FlagResult, error) {
+	var result = &views.FlagResult{}
+	stages, err := s.flagService.FindByChallengeId(ctx, instance.ChallengeId)
+	if err != nil {
+		return nil, err
 	}
-	if !strings.EqualFold(instance.Flag, flag) {
-		return false, nil
+	if len(stages) == 0 {
+		challenge, err := s.challengeService.FindById(ctx, instance.ChallengeId)
+		if err != nil {
+			return nil, err
+		}
+		if !flagmatch.Match(challenge.FlagMatch, instance.Flag, flag, challenge.FlagStripWrapper) {
+			return result, nil
+		}
+		result.Ok = true
+	} else {
+		stage := s.flagService.Match(instance, stages, flag)
+		if stage == nil {
+			return result, nil
+		}
+		if err := s.flagService.Capture(ctx, instance.UserId, *stage); err != nil {
+			return nil, err
+		}
+		captured, completed, err := s.flagService.Progress(ctx, instance.UserId, instance.ChallengeId, stages)
+		if err != nil {
+			return nil, err
+		}
+		result.Ok = true
+		result.Stage = stage.Name
+		result.Captured = captured
+		if !completed {
+			return result, nil
+		}
 	}
 
 	// 通过 ✅
+	if err := s.saveSolve(ctx, instance, len(stages) > 0); err != nil {
+		return nil, err
+	}
+	result.Solved = true
+	return result, nil
+}
+
+// saveSolve 保存通关记录，staged 为题目是否配置了阶段，此时分数已在提交阶段时获得
+func (s *InstanceService) saveSolve(ctx context.Context, instance models.Instance, staged bool) error {
 	challenge, err := s.challengeService.FindById(ctx, instance.ChallengeId)
 	if err != nil {
-		return false, err
+		return err
 	}
 	// 查询是否通关过
 	items, err := s.solveService.FindByChallengeIdAndUserId(ctx, instance.ChallengeId, instance.UserId)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	// 保存通关记录
 	now := time.Now()
 	usedTime := now.Sub(time.UnixMilli(instance.CreatedAt))
 
@@ -370,29 +482,30 @@ func (s *InstanceService) SubmitFlag(ctx context.Context, id, flag string) (bool
 		// 只能刷耗时，但是不能更改一血的通关时间
 		solve.UsedTime = usedTime.Milliseconds()
 		solve.UsedTimeStr = usedTime.Truncate(time.Second).String()
-		err = s.solveService.UpdateById(ctx, &solve)
-		if err != nil {
-			return false, err
-		}
-	} else {
-		ps := &models.Solve{
-			ID:          uuid.NewString(),
-			UserId:      instance.UserId,
-			ChallengeId: instance.ChallengeId,
-			Points:      challenge.Points,
-			StartAt:     instance.CreatedAt,
-			SolvedAt:    now.UnixMilli(),
-			UsedTime:    usedTime.Milliseconds(),
-			UsedTimeStr: usedTime.Truncate(time.Second).String(), // 只保留到秒
-		}
-		err = s.solveService.Create(ctx, ps)
-		if err != nil {
-			return false, err
-		}
+		return s.solveService.UpdateById(ctx, &solve)
 	}
-	// 销毁环境
-	_ = s.Destroy(ctx, id)
-	return true, nil
+
+	solves, err := s.solveService.CountByChallengeId(ctx, instance.ChallengeId)
+	if err != nil {
+		return err
+	}
+	ps := &models.Solve{
+		ID:          uuid.NewString(),
+		UserId:      instance.UserId,
+		ChallengeId: instance.ChallengeId,
+		Points:      challenge.SolvePoints(solves+1, staged),
+		Bonus:       challenge.Bonus(solves + 1),
+		SolveOrder:  solves + 1,
+		StartAt:     instance.CreatedAt,
+		SolvedAt:    now.UnixMilli(),
+		UsedTime:    usedTime.Milliseconds(),
+		UsedTimeStr: usedTime.Truncate(time.Second).String(), // 只保留到秒
+	}
+	if err := s.solveService.Create(ctx, ps); err != nil {
+		return err
+	}
+	// 动态计分时之前的解题者同步降分
+	return s.solveService.SyncPoints(ctx, challenge)
 }
 
 func (s *InstanceService) FindByIdWithNoNotExistsError(ctx context.Context, id string) (*models.Instance, error) {
@@ -448,11 +561,12 @@ func (s *InstanceService) startContainer(ctx context.Context, id string) error {
 			continue
 		}
 
+		var target string
 		if s.conf.Gateway.Enabled {
-			var localAddr = "127.0.0.1:" + ports[0]
-			s.reverseProxyService.AddApp(instance.Subdomain, App{
-				Host:     localAddr,
-				Protocol: "http",
+			// 记录转发地址，独立部署的网关从数据库同步路由
+			target = s.conf.Gateway.Upstream(ports[0])
+			_ = s.UpdateColumnsById(ctx, id, orz.Map{
+				"target": target,
 			})
 		} else {
 			var accessUrl = ports[0]
@@ -463,6 +577,12 @@ func (s *InstanceService) startContainer(ctx context.Context, id string) error {
 
 		started = true
 		_ = s.UpdateStatus(ctx, id, models.InstanceStatusRunning, "")
+		if target != "" {
+			s.reverseProxyService.AddApp(instance.Subdomain, App{
+				Host:     target,
+				Protocol: instance.UpstreamProtocol(),
+			})
+		}
 	}
 
 	now := time.Now()
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/dushixiang/cyberpoc/internal/cyber/models"
@@ -16,38 +17,67 @@ type RankService struct {
 	*orz.Service
 	*repo.RankRepo
 	*repo.SolveRepo
+	hintUnlockRepo  *repo.HintUnlockRepo
+	flagCaptureRepo *repo.FlagCaptureRepo
+	challengeRepo   *repo.ChallengeRepo
+	solveService    *SolveService
 }
 
-func NewRankService(db *gorm.DB) *RankService {
+func NewRankService(db *gorm.DB, solveService *SolveService) *RankService {
 	return &RankService{
-		Service:   orz.NewService(db),
-		RankRepo:  repo.NewRankRepo(db),
-		SolveRepo: repo.NewSolveRepo(db),
+		Service:         orz.NewService(db),
+		RankRepo:        repo.NewRankRepo(db),
+		SolveRepo:       repo.NewSolveRepo(db),
+		hintUnlockRepo:  repo.NewHintUnlockRepo(db),
+		flagCaptureRepo: repo.NewFlagCaptureRepo(db),
+		challengeRepo:   repo.NewChallengeRepo(db),
+		solveService:    solveService,
 	}
 }
 
-// Recompute 每次全量重算积分：按用户汇总 Solve 的 points 之和为 score，仅保留前100
+// Recompute 每次全量重算积分：先把动态计分题目的当前分值同步到全部通关记录，再按 rankUsers 计算得分，仅保留前100
 func (s RankService) Recompute(ctx context.Context) error {
-	// 直接用聚合并排序，选前100
-	db := s.RankRepo.GetDB(ctx)
-	type agg struct {
-		UserId       string
-		Score        int64
-		TotalTime    int64
-		TotalTimeStr string
+	challenges, err := s.challengeRepo.FindByScoreType(ctx, models.ScoreTypeDynamic)
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		if err := s.solveService.SyncPoints(ctx, challenge); err != nil {
+			return err
+		}
+	}
+	if err := s.solveService.BackfillOrders(ctx); err != nil {
+		return err
 	}
-	var aggs []agg
-	err := db.WithContext(ctx).
+	// 修正配置阶段之前已经记录了分值的通关记录
+	if err := s.SolveRepo.ClearStagedPoints(ctx); err != nil {
+		return err
+	}
+
+	db := s.RankRepo.GetDB(ctx)
+	var aggs []rankEntry
+	err = db.WithContext(ctx).
 		Table("solves").
-		Select("user_id as user_id, sum(points) as score, sum(used_time) as total_time").
+		Select("user_id as user_id, sum(points + bonus) as score, sum(used_time) as total_time").
 		Group("user_id").
-		Order("score desc, total_time asc").
-		Limit(100).
 		Find(&aggs).Error
 	if err != nil {
 		return err
 	}
 
+	captures, err := s.flagCaptureRepo.SumPointsGroupByUserId(ctx)
+	if err != nil {
+		return err
+	}
+	costs, err := s.hintUnlockRepo.SumCostGroupByUserId(ctx)
+	if err != nil {
+		return err
+	}
+	aggs = rankUsers(aggs, captures, costs)
+	if len(aggs) > 100 {
+		aggs = aggs[:100]
+	}
+
 	// 清空 ranks 后批量写入前100
 	if err := db.Exec("truncate table ranks").Error; err != nil {
 		_ = db.Exec("delete from ranks").Error
@@ -67,6 +97,7 @@ func (s RankService) Recompute(ctx context.Context) error {
 			Score:        a.Score,
 			TotalTime:    totalTimeSeconds,
 			TotalTimeStr: totalTimeStr,
+			Partial:      a.Partial,
 			UpdatedAt:    now,
 		})
 	}
@@ -78,6 +109,47 @@ func (s RankService) Recompute(ctx context.Context) error {
 	return nil
 }
 
+// rankEntry 排行榜中的用户，Score 查询时为通关记录的 points 与 bonus 之和
+type rankEntry struct {
+	UserId    string
+	Score     int64
+	TotalTime int64
+	Partial   bool // 只完成了部分阶段，没有通关记录
+}
+
+// userScore 用户得分：通关记录的 points 与 bonus 之和，加上提交阶段 Flag 的分数，扣除解锁提示的分数。
+// 配置了阶段的题目通关记录分值为 0，总分由阶段分数组成，排行榜和解锁条件都按此计算
+func userScore(solved, captured, cost int64) int64 {
+	return solved + captured - cost
+}
+
+// rankUsers 计算用户得分并排序，同分时通关的用户在只完成部分阶段的用户之前，再按耗时排序
+func rankUsers(solved []rankEntry, captures, costs map[string]int64) []rankEntry {
+	var ranked = make(map[string]bool, len(solved))
+	for i := range solved {
+		ranked[solved[i].UserId] = true
+		solved[i].Score = userScore(solved[i].Score, captures[solved[i].UserId], costs[solved[i].UserId])
+	}
+	for userId, points := range captures {
+		if !ranked[userId] {
+			solved = append(solved, rankEntry{UserId: userId, Score: userScore(0, points, costs[userId]), Partial: true})
+		}
+	}
+	sort.SliceStable(solved, func(i, j int) bool {
+		if solved[i].Score != solved[j].Score {
+			return solved[i].Score > solved[j].Score
+		}
+		if solved[i].Partial != solved[j].Partial {
+			return !solved[i].Partial
+		}
+		if solved[i].TotalTime != solved[j].TotalTime {
+			return solved[i].TotalTime < solved[j].TotalTime
+		}
+		return solved[i].UserId < solved[j].UserId
+	})
+	return solved
+}
+
 func (s RankService) List(ctx context.Context, limit int) (items []views.RankView, err error) {
 	return s.RankRepo.List(ctx, limit)
 }
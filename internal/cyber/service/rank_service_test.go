@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+)
+
+func TestRankUsers(t *testing.T) {
+	staged := models.Challenge{Points: 500}
+	static := models.Challenge{Points: 300}
+	// alice 提交了 user(100)、root(200) 两个阶段并通关，通关记录不计分，只有一血奖励 20
+	// bob 只提交了 user 阶段；carol 通关了静态题目并解锁了一个 50 分的提示
+	solved := []rankEntry{
+		{UserId: "alice", Score: staged.SolvePoints(1, true) + 20, TotalTime: 300},
+		{UserId: "carol", Score: static.SolvePoints(1, false), TotalTime: 100},
+	}
+	captures := map[string]int64{"alice": 300, "bob": 100}
+	costs := map[string]int64{"carol": 50}
+
+	got := rankUsers(solved, captures, costs)
+	want := []rankEntry{
+		{UserId: "alice", Score: 320, TotalTime: 300},
+		{UserId: "carol", Score: 250, TotalTime: 100},
+		{UserId: "bob", Score: 100, Partial: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rankUsers() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("rankUsers()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
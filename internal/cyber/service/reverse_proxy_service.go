@@ -1,21 +1,32 @@
 package service
 
 import (
+	"context"
 	"crypto/tls"
 	_ "embed"
+	"html/template"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-func NewReverseProxyService(logger *zap.Logger) *ReverseProxyService {
+func NewReverseProxyService(logger *zap.Logger, db *gorm.DB, conf *config.Config) *ReverseProxyService {
 	service := &ReverseProxyService{
-		logger: logger,
+		InstanceRepo:        repo.NewInstanceRepo(db),
+		challengeRecordRepo: repo.NewChallengeRecordRepo(db),
+		logger:              logger,
+		conf:                conf,
 	}
 	reverseProxy := &httputil.ReverseProxy{Director: service.director}
 	var InsecureTransport http.RoundTripper = &http.Transport{
@@ -27,14 +38,21 @@ func NewReverseProxyService(logger *zap.Logger) *ReverseProxyService {
 		TLSHandshakeTimeout: 10 * time.Second,
 	}
 	reverseProxy.Transport = InsecureTransport
+	if conf.Gateway.PathMode() {
+		reverseProxy.ModifyResponse = service.modifyResponse
+	}
 	service.reverseProxy = reverseProxy
 	return service
 }
 
 type ReverseProxyService struct {
+	*repo.InstanceRepo
+	challengeRecordRepo *repo.ChallengeRecordRepo
+
 	logger       *zap.Logger
+	conf         *config.Config
 	reverseProxy *httputil.ReverseProxy
-	apps         sync.Map
+	apps         sync.Map // key 为子域名，value 为 appEntry
 }
 
 type App struct {
@@ -42,42 +60,169 @@ type App struct {
 	Protocol string
 }
 
+// appEntry 路由表中的实例，addedAt 用于同步时跳过查询数据库之后才加入的路由
+type appEntry struct {
+	App     App
+	addedAt time.Time
+}
+
+// route 本次请求命中的实例
+type route struct {
+	Key    string
+	App    App
+	Prefix string // 路径模式下的访问前缀 /i/<subdomain>
+}
+
+type routeContextKey struct{}
+
 func (s *ReverseProxyService) AddApp(key string, app App) {
-	s.apps.Store(key, app)
+	s.apps.Store(key, appEntry{App: app, addedAt: time.Now()})
 }
 
 func (s *ReverseProxyService) DelApp(key string) {
 	s.apps.Delete(key)
 }
 
+func (s *ReverseProxyService) HasApp(key string) bool {
+	_, ok := s.apps.Load(key)
+	return ok
+}
+
+// Resolvable 子域名是否对应启动中或运行中的实例，优先使用路由表，只有符合实例子域名格式且未命中路由表时才查询数据库
+func (s *ReverseProxyService) Resolvable(ctx context.Context, subdomain string) (bool, error) {
+	if s.HasApp(subdomain) {
+		return true, nil
+	}
+	if !isInstanceSubdomain(subdomain) {
+		return false, nil
+	}
+	return s.InstanceRepo.ExistsBySubdomainAndStatusIn(ctx, subdomain, []models.InstanceStatus{
+		models.InstanceStatusCreating,
+		models.InstanceStatusRunning,
+	})
+}
+
+// Sync 以数据库中运行中的实例为准重建路由表，查询之后才加入的路由（如刚启动的实例）留到下次同步再确认
+func (s *ReverseProxyService) Sync(ctx context.Context) error {
+	snapshotAt := time.Now()
+	instances, err := s.InstanceRepo.FindRoutable(ctx)
+	if err != nil {
+		return err
+	}
+	var keys = make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		keys[instance.Subdomain] = true
+		app := App{
+			Host:     instance.Target,
+			Protocol: instance.UpstreamProtocol(),
+		}
+		if value, ok := s.apps.Load(instance.Subdomain); ok && value.(appEntry).App == app {
+			continue
+		}
+		s.AddApp(instance.Subdomain, app)
+	}
+	s.apps.Range(func(key, value any) bool {
+		if !keys[key.(string)] && value.(appEntry).addedAt.Before(snapshotAt) {
+			s.apps.CompareAndDelete(key, value)
+		}
+		return true
+	})
+	return nil
+}
+
+// StartSync 定时从数据库同步路由，网关多副本部署时各自独立同步
+func (s *ReverseProxyService) StartSync(ctx context.Context) {
+	interval := time.Duration(s.conf.Gateway.SyncInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			s.logger.Warn("gateway sync routes", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *ReverseProxyService) director(req *http.Request) {
-	host := req.Host
-	parts := strings.Split(host, ".")
+	rt := req.Context().Value(routeContextKey{}).(route)
+	req.URL.Scheme = rt.App.Protocol
+	req.URL.Host = rt.App.Host
+	if rt.Prefix != "" {
+		req.Header.Set("X-Forwarded-Prefix", rt.Prefix)
+	}
+}
 
-	appKey := parts[0]
-	value, _ := s.apps.Load(appKey)
-	app := value.(App)
-	req.URL.Scheme = app.Protocol
-	req.URL.Host = app.Host
+// resolvePath 路径模式下解析实例，命中 /i/<subdomain>/ 时去掉前缀；
+// 页面中以 / 开头的绝对路径请求没有前缀，按同一网关下 Referer 的前缀转发到来源实例
+func (s *ReverseProxyService) resolvePath(r *http.Request) (key string, rest string, ok bool) {
+	if after, found := strings.CutPrefix(r.URL.Path, config.GatewayPathPrefix); found {
+		key, rest, _ = strings.Cut(after, "/")
+		return key, "/" + rest, key != ""
+	}
+	referer, err := url.Parse(r.Referer())
+	if err != nil || referer.Host != r.Host {
+		return "", "", false
+	}
+	if after, found := strings.CutPrefix(referer.Path, config.GatewayPathPrefix); found {
+		if key, _, found = strings.Cut(after, "/"); found {
+			return key, r.URL.Path, false
+		}
+	}
+	return "", "", false
 }
 
 func (s *ReverseProxyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	host := r.Host
-	//request, _ := httputil.DumpRequest(r, false)
-	//s.logger.Sugar().Debug(string(request))
-	parts := strings.Split(host, ".")
-	if len(parts) < 2 {
-		_, _ = w.Write([]byte(`bad request`))
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	var (
+		appKey string
+		prefix string
+	)
+	if s.conf.Gateway.PathMode() {
+		key, rest, matched := s.resolvePath(r)
+		if key == "" {
+			s.renderPage(w, unknownPage)
+			return
+		}
+		if matched {
+			prefix = config.GatewayPathPrefix + key
+			if r.URL.Path == prefix {
+				// 补全末尾的 /，保证页面中的相对路径可以正确解析
+				target := prefix + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusFound)
+				return
+			}
+			r.URL.Path = rest
+			r.URL.RawPath = ""
+		} else {
+			prefix = config.GatewayPathPrefix + key
+		}
+		appKey = key
+	} else {
+		parts := strings.Split(r.Host, ".")
+		if len(parts) < 2 {
+			s.renderPage(w, badRequestPage)
+			return
+		}
+		appKey = parts[0]
 	}
-	appKey := parts[0]
-	_, ok := s.apps.Load(appKey)
+
+	value, ok := s.apps.Load(appKey)
 	if !ok {
-		_, _ = w.Write(errorPage)
-		w.WriteHeader(http.StatusNotFound)
+		s.renderPage(w, s.missPage(r.Context(), appKey))
 		return
 	}
+	rt := route{Key: appKey, App: value.(appEntry).App, Prefix: prefix}
+	r = r.WithContext(context.WithValue(r.Context(), routeContextKey{}, rt))
+
 	start := time.Now()
 	s.reverseProxy.ServeHTTP(w, r)
 	s.logger.Sugar().Debugf(
@@ -89,8 +234,146 @@ func (s *ReverseProxyService) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	)
 }
 
+// modifyResponse 路径模式下把跳转地址和 Cookie 路径改写到实例前缀下
+func (s *ReverseProxyService) modifyResponse(resp *http.Response) error {
+	rt, ok := resp.Request.Context().Value(routeContextKey{}).(route)
+	if !ok || rt.Prefix == "" {
+		return nil
+	}
+
+	if location := resp.Header.Get("Location"); location != "" {
+		resp.Header.Set("Location", rewriteLocation(location, rt, resp.Request.Host))
+	}
+
+	cookies := resp.Header.Values("Set-Cookie")
+	if len(cookies) > 0 {
+		resp.Header.Del("Set-Cookie")
+		for _, raw := range cookies {
+			resp.Header.Add("Set-Cookie", rewriteCookiePath(raw, rt.Prefix))
+		}
+	}
+	return nil
+}
+
+func rewriteLocation(location string, rt route, gatewayHost string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	if u.IsAbs() {
+		// 只改写指向实例自身的地址
+		if u.Host != rt.App.Host && u.Host != gatewayHost {
+			return location
+		}
+		u.Scheme = ""
+		u.Host = ""
+	} else if !strings.HasPrefix(u.Path, "/") {
+		// 相对路径由浏览器基于当前地址解析，无需处理
+		return location
+	}
+	if strings.HasPrefix(u.Path, rt.Prefix+"/") {
+		return u.String()
+	}
+	u.Path = rt.Prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = rt.Prefix + u.RawPath
+	}
+	return u.String()
+}
+
+func rewriteCookiePath(raw string, prefix string) string {
+	cookie, err := http.ParseSetCookie(raw)
+	if err != nil {
+		return raw
+	}
+	if cookie.Path == "" || cookie.Path == "/" {
+		cookie.Path = prefix + "/"
+	} else if !strings.HasPrefix(cookie.Path, prefix+"/") {
+		cookie.Path = prefix + cookie.Path
+	}
+	// 实例的 Domain 属性对网关地址无效
+	cookie.Domain = ""
+	return cookie.String()
+}
+
+// gatewayPage 网关未能转发时展示的页面
+type gatewayPage struct {
+	Code    int
+	Title   string
+	Message string
+	Refresh int // 自动刷新间隔，单位：秒
+}
+
+var (
+	badRequestPage = gatewayPage{
+		Code:  http.StatusBadRequest,
+		Title: "bad request",
+	}
+	unknownPage = gatewayPage{
+		Code:    http.StatusNotFound,
+		Title:   "the environment does not exist",
+		Message: "请检查访问地址是否正确",
+	}
+	creatingPage = gatewayPage{
+		Code:    http.StatusServiceUnavailable,
+		Title:   "the environment is starting",
+		Message: "环境正在启动，页面会自动刷新",
+		Refresh: 3,
+	}
+	createFailurePage = gatewayPage{
+		Code:    http.StatusBadGateway,
+		Title:   "the environment failed to start",
+		Message: "环境启动失败，请销毁后重新启动",
+	}
+	gonePage = gatewayPage{
+		Code:    http.StatusGone,
+		Title:   "the environment has expired or been destroyed",
+		Message: "环境已过期或已被销毁，请重新启动",
+	}
+)
+
+// missPage 根据实例状态选择未命中路由时的页面，不是实例子域名格式的请求不查询数据库
+func (s *ReverseProxyService) missPage(ctx context.Context, subdomain string) gatewayPage {
+	if !isInstanceSubdomain(subdomain) {
+		return unknownPage
+	}
+	status, exists, err := s.challengeRecordRepo.FindInstanceStatusBySubdomain(ctx, subdomain)
+	if err != nil {
+		s.logger.Warn("gateway find instance status", zap.String("subdomain", subdomain), zap.Error(err))
+		return unknownPage
+	}
+	if !exists {
+		return unknownPage
+	}
+	switch status {
+	case models.InstanceStatusCreateFailure:
+		return createFailurePage
+	case "", models.InstanceStatusDeleting, models.InstanceStatusDeleteFailure:
+		// 实例已删除或正在删除，但启动记录还在
+		return gonePage
+	default:
+		// 运行中但路由尚未同步到当前网关时同样等待刷新
+		return creatingPage
+	}
+}
+
+func (s *ReverseProxyService) renderPage(w http.ResponseWriter, page gatewayPage) {
+	header := w.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+	if page.Refresh > 0 {
+		header.Set("Retry-After", strconv.Itoa(page.Refresh))
+	}
+	w.WriteHeader(page.Code)
+	if err := errorPageTemplate.Execute(w, page); err != nil {
+		s.logger.Warn("gateway render page", zap.Error(err))
+	}
+}
+
 //go:embed reverse_proxy_service_error_page.html
-var errorPage []byte
+var errorPage string
+
+var errorPageTemplate = template.Must(template.New("error_page").Parse(errorPage))
 
 func (s *ReverseProxyService) ProxyRequestHandler() func(http.ResponseWriter, *http.Request) {
 	return s.ServeHTTP
@@ -1,7 +1,13 @@
 package service
 
 import (
+	"context"
+	"errors"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
 	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/internal/identity"
 	"github.com/go-orz/orz"
 	"gorm.io/gorm"
 )
@@ -9,11 +15,82 @@ import (
 type SolveService struct {
 	*orz.Service
 	*repo.SolveRepo
+	challengeRepo     *repo.ChallengeRepo
+	challengeFlagRepo *repo.ChallengeFlagRepo
 }
 
 func NewSolveService(db *gorm.DB) *SolveService {
 	return &SolveService{
-		Service:   orz.NewService(db),
-		SolveRepo: repo.NewSolveRepo(db),
+		Service:           orz.NewService(db),
+		SolveRepo:         repo.NewSolveRepo(db),
+		challengeRepo:     repo.NewChallengeRepo(db),
+		challengeFlagRepo: repo.NewChallengeFlagRepo(db),
+	}
+}
+
+// SyncPoints 动态计分的题目按当前解题人数重新计算分值，并同步到该题全部通关记录，配置了阶段的题目不计通关分值
+func (s *SolveService) SyncPoints(ctx context.Context, challenge models.Challenge) error {
+	if !challenge.Dynamic() {
+		return nil
+	}
+	staged, err := s.challengeFlagRepo.ExistsByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return err
+	}
+	count, err := s.SolveRepo.CountByChallengeId(ctx, challenge.ID)
+	if err != nil {
+		return err
+	}
+	return s.SolveRepo.UpdatePointsByChallengeId(ctx, challenge.ID, challenge.SolvePoints(count, staged))
+}
+
+// FindUserView 用户的公开信息
+func (s *SolveService) FindUserView(ctx context.Context, userId string) (views.UserView, bool, error) {
+	user, err := identity.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return views.UserView{}, false, nil
+		}
+		return views.UserView{}, false, err
+	}
+	return views.UserView{
+		ID:     user.ID,
+		Name:   user.Name,
+		Avatar: user.Avatar,
+	}, true, nil
+}
+
+// BackfillOrders 为缺少解题名次的历史记录按通过时间补齐名次和奖励
+func (s *SolveService) BackfillOrders(ctx context.Context) error {
+	challengeIds, err := s.SolveRepo.FindChallengeIdsWithoutOrder(ctx)
+	if err != nil {
+		return err
+	}
+	for _, challengeId := range challengeIds {
+		challenge, exists, err := s.challengeRepo.FindByIdExists(ctx, challengeId)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		solves, err := s.SolveRepo.FindByChallengeIdOrderBySolvedAt(ctx, challengeId)
+		if err != nil {
+			return err
+		}
+		for i, solve := range solves {
+			order := int64(i + 1)
+			if solve.SolveOrder == order {
+				continue
+			}
+			err := s.SolveRepo.UpdateColumnsById(ctx, solve.ID, orz.Map{
+				"solve_order": order,
+				"bonus":       challenge.Bonus(order),
+			})
+			if err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/config"
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/pkg/limiter"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+)
+
+const (
+	defaultSubmitUserLimit      = 30
+	defaultSubmitChallengeLimit = 10
+	defaultSubmitIPLimit        = 60
+	defaultSubmitWindow         = 60
+	defaultSubmitCooldown       = 30
+	defaultSubmitMaxCooldown    = 3600
+)
+
+// SubmissionLimitService 限制 Flag 提交频率，防止暴力破解，计数只保存在内存中
+type SubmissionLimitService struct {
+	limiter *limiter.Limiter
+
+	userLimit      int
+	challengeLimit int
+	ipLimit        int
+	window         time.Duration
+	cooldown       time.Duration
+	maxCooldown    time.Duration
+}
+
+func NewSubmissionLimitService(conf *config.Config) *SubmissionLimitService {
+	submission := conf.Submission
+	return &SubmissionLimitService{
+		limiter:        limiter.New(),
+		userLimit:      cmp.Or(submission.UserLimit, defaultSubmitUserLimit),
+		challengeLimit: cmp.Or(submission.ChallengeLimit, defaultSubmitChallengeLimit),
+		ipLimit:        cmp.Or(submission.IPLimit, defaultSubmitIPLimit),
+		window:         time.Duration(cmp.Or(submission.Window, defaultSubmitWindow)) * time.Second,
+		cooldown:       time.Duration(cmp.Or(submission.Cooldown, defaultSubmitCooldown)) * time.Second,
+		maxCooldown:    time.Duration(cmp.Or(submission.MaxCooldown, defaultSubmitMaxCooldown)) * time.Second,
+	}
+}
+
+// Take 记录一次提交，超出任一限制时返回 xe.RateLimitError
+func (s *SubmissionLimitService) Take(accountId, ip string, challenge models.Challenge) error {
+	challengeLimit := s.challengeLimit
+	if challenge.SubmitLimit != 0 {
+		challengeLimit = challenge.SubmitLimit
+	}
+	wait := s.limiter.Take(
+		limiter.Key{Name: "user:" + accountId, Rule: s.rule(s.userLimit)},
+		limiter.Key{Name: "challenge:" + accountId + ":" + challenge.ID, Rule: s.rule(challengeLimit)},
+		limiter.Key{Name: "ip:" + ip, Rule: s.rule(s.ipLimit)},
+	)
+	if wait > 0 {
+		return xe.NewRateLimitError(wait)
+	}
+	return nil
+}
+
+func (s *SubmissionLimitService) rule(limit int) limiter.Rule {
+	return limiter.Rule{
+		Limit:       limit,
+		Window:      s.window,
+		Cooldown:    s.cooldown,
+		MaxCooldown: s.maxCooldown,
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSubmissionFlag 提交内容最多保存的字符数
+	maxSubmissionFlag = 1024
+	// wrongAnswerLimit 统计中返回的错误答案数量
+	wrongAnswerLimit = 20
+)
+
+type SubmissionService struct {
+	*orz.Service
+	*repo.SubmissionRepo
+	logger *zap.Logger
+}
+
+func NewSubmissionService(logger *zap.Logger, db *gorm.DB) *SubmissionService {
+	return &SubmissionService{
+		Service:        orz.NewService(db),
+		SubmissionRepo: repo.NewSubmissionRepo(db),
+		logger:         logger,
+	}
+}
+
+// Record 记录一次提交，result 为空表示提交过于频繁被拒绝；记录失败只写日志，不影响提交结果
+func (s *SubmissionService) Record(ctx context.Context, userId, challengeId, instanceId, flag, ip string, result *views.FlagResult) {
+	if runes := []rune(flag); len(runes) > maxSubmissionFlag {
+		flag = string(runes[:maxSubmissionFlag])
+	}
+	submission := models.Submission{
+		ID:          uuid.NewString(),
+		UserId:      userId,
+		ChallengeId: challengeId,
+		InstanceId:  instanceId,
+		Flag:        flag,
+		Limited:     result == nil,
+		IP:          ip,
+	}
+	if result != nil {
+		submission.Correct = result.Ok
+		submission.Stage = result.Stage
+	}
+	if err := s.SubmissionRepo.Create(ctx, &submission); err != nil {
+		s.logger.Warn("record submission", zap.String("user_id", userId), zap.String("challenge_id", challengeId), zap.Error(err))
+	}
+}
+
+// Paging 分页查询提交记录
+func (s *SubmissionService) Paging(ctx context.Context, offset, limit int, filter repo.SubmissionFilter) ([]views.SubmissionView, int64, error) {
+	total, err := s.SubmissionRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := s.SubmissionRepo.Find(ctx, offset, limit, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Stats 题目的提交统计及最常见的错误答案
+func (s *SubmissionService) Stats(ctx context.Context, challengeId string) (views.SubmissionStats, error) {
+	stats, err := s.SubmissionRepo.StatsByChallengeId(ctx, challengeId)
+	if err != nil {
+		return stats, err
+	}
+	stats.WrongAnswers, err = s.SubmissionRepo.GroupWrongByChallengeId(ctx, challengeId, wrongAnswerLimit)
+	if err != nil {
+		return stats, err
+	}
+	if stats.WrongAnswers == nil {
+		stats.WrongAnswers = []views.WrongAnswer{}
+	}
+	return stats, nil
+}
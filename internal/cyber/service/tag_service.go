@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TagService 题目标签，题目与标签多对多关联
+type TagService struct {
+	*orz.Service
+	*repo.TagRepo
+	challengeTagRepo *repo.ChallengeTagRepo
+}
+
+func NewTagService(db *gorm.DB) *TagService {
+	return &TagService{
+		Service:          orz.NewService(db),
+		TagRepo:          repo.NewTagRepo(db),
+		challengeTagRepo: repo.NewChallengeTagRepo(db),
+	}
+}
+
+// NormalizeTags 去掉首尾空白，忽略大小写去重，保留第一次出现的写法
+func NormalizeTags(names []string) ([]string, error) {
+	var (
+		items = make([]string, 0, len(names))
+		seen  = make(map[string]bool, len(names))
+	)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || utf8.RuneCountInString(name) > 64 {
+			return nil, xe.ErrInvalidTag
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		items = append(items, name)
+	}
+	return items, nil
+}
+
+// SetTags 覆盖题目的标签，不存在的标签自动新建
+func (s *TagService) SetTags(ctx context.Context, challengeId string, names []string) error {
+	names, err := NormalizeTags(names)
+	if err != nil {
+		return err
+	}
+	return s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.challengeTagRepo.DeleteByChallengeId(ctx, challengeId); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		tags, err := s.TagRepo.FindByNameIn(ctx, names)
+		if err != nil {
+			return err
+		}
+		var ids = make(map[string]string, len(tags))
+		for _, tag := range tags {
+			ids[strings.ToLower(tag.Name)] = tag.ID
+		}
+		for _, name := range names {
+			id, ok := ids[strings.ToLower(name)]
+			if !ok {
+				tag := models.Tag{ID: uuid.NewString(), Name: name}
+				if err := s.TagRepo.Create(ctx, &tag); err != nil {
+					return err
+				}
+				id = tag.ID
+			}
+			if err := s.challengeTagRepo.Create(ctx, &models.ChallengeTag{
+				ID:          uuid.NewString(),
+				ChallengeId: challengeId,
+				TagId:       id,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Names 题目的标签名称
+func (s *TagService) Names(ctx context.Context, challengeId string) ([]string, error) {
+	names, err := s.NamesByChallengeIdIn(ctx, []string{challengeId})
+	if err != nil {
+		return nil, err
+	}
+	return names[challengeId], nil
+}
+
+// NamesByChallengeIdIn 按题目分组的标签名称
+func (s *TagService) NamesByChallengeIdIn(ctx context.Context, challengeIds []string) (map[string][]string, error) {
+	var names = make(map[string][]string, len(challengeIds))
+	if len(challengeIds) == 0 {
+		return names, nil
+	}
+	items, err := s.challengeTagRepo.FindNamesByChallengeIdIn(ctx, challengeIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		names[item.ChallengeId] = append(names[item.ChallengeId], item.Name)
+	}
+	return names, nil
+}
+
+// Delete 删除标签及其与题目的关联
+func (s *TagService) Delete(ctx context.Context, id string) error {
+	return s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.challengeTagRepo.DeleteByTagId(ctx, id); err != nil {
+			return err
+		}
+		return s.TagRepo.DeleteById(ctx, id)
+	})
+}
+
+// DeleteByChallengeId 删除题目的全部标签关联
+func (s *TagService) DeleteByChallengeId(ctx context.Context, challengeId string) error {
+	return s.challengeTagRepo.DeleteByChallengeId(ctx, challengeId)
+}
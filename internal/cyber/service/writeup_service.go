@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/cyberpoc/internal/cyber/models"
+	"github.com/dushixiang/cyberpoc/internal/cyber/repo"
+	"github.com/dushixiang/cyberpoc/internal/cyber/views"
+	"github.com/dushixiang/cyberpoc/pkg/richtext"
+	"github.com/dushixiang/cyberpoc/pkg/xe"
+	"github.com/go-orz/orz"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxWriteupSize 题解内容的最大长度
+const maxWriteupSize = 64 * 1024
+
+type WriteupService struct {
+	*orz.Service
+	*repo.WriteupRepo
+	solveRepo *repo.SolveRepo
+}
+
+func NewWriteupService(db *gorm.DB) *WriteupService {
+	return &WriteupService{
+		Service:     orz.NewService(db),
+		WriteupRepo: repo.NewWriteupRepo(db),
+		solveRepo:   repo.NewSolveRepo(db),
+	}
+}
+
+// checkSolved 只有通关的玩家才能提交和查看题解
+func (s *WriteupService) checkSolved(ctx context.Context, challengeId, userId string) error {
+	count, err := s.solveRepo.CountByChallengeIdAndUserId(ctx, challengeId, userId)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return xe.ErrWriteupNotSolved
+	}
+	return nil
+}
+
+// Submit 提交或修改题解，修改后需要重新审核
+func (s *WriteupService) Submit(ctx context.Context, challengeId, userId, content string) (models.Writeup, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || len(content) > maxWriteupSize {
+		return models.Writeup{}, xe.ErrInvalidWriteup
+	}
+	if err := s.checkSolved(ctx, challengeId, userId); err != nil {
+		return models.Writeup{}, err
+	}
+
+	item, exists, err := s.WriteupRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+	if err != nil {
+		return item, err
+	}
+	if !exists {
+		item = models.Writeup{
+			ID:          uuid.NewString(),
+			ChallengeId: challengeId,
+			UserId:      userId,
+			Content:     content,
+			Status:      models.WriteupStatusPending,
+		}
+		return item, s.WriteupRepo.Create(ctx, &item)
+	}
+	item.Content = content
+	item.Status = models.WriteupStatusPending
+	item.Reason = ""
+	item.ReviewerId = ""
+	item.ReviewedAt = 0
+	return item, s.WriteupRepo.Save(ctx, &item)
+}
+
+// Mine 玩家自己在某道题下的题解
+func (s *WriteupService) Mine(ctx context.Context, challengeId, userId string) (models.Writeup, error) {
+	item, exists, err := s.WriteupRepo.FindByChallengeIdAndUserId(ctx, challengeId, userId)
+	if err != nil {
+		return item, err
+	}
+	if !exists {
+		return item, xe.ErrWriteupNotFound
+	}
+	return item, nil
+}
+
+// Approved 审核通过的题解，只对通关的玩家可见
+func (s *WriteupService) Approved(ctx context.Context, challengeId, userId string) ([]views.WriteupView, error) {
+	if err := s.checkSolved(ctx, challengeId, userId); err != nil {
+		return nil, err
+	}
+	items, err := s.WriteupRepo.FindByChallengeIdAndStatus(ctx, challengeId, models.WriteupStatusApproved)
+	if err != nil {
+		return nil, err
+	}
+	return renderWriteups(items), nil
+}
+
+// Paging 管理端分页查询题解，status、challengeId 为空时不过滤
+func (s *WriteupService) Paging(ctx context.Context, offset, limit int, status, challengeId string) ([]views.WriteupView, int64, error) {
+	items, total, err := s.WriteupRepo.Paging(ctx, offset, limit, status, challengeId)
+	if err != nil {
+		return nil, 0, err
+	}
+	return renderWriteups(items), total, nil
+}
+
+// renderWriteups 题解由玩家编写，在服务端渲染 markdown 并清理，避免展示给其他玩家和管理员时执行脚本
+func renderWriteups(items []views.WriteupView) []views.WriteupView {
+	for i := range items {
+		items[i].ContentHtml = richtext.Markdown(items[i].Content)
+	}
+	return items
+}
+
+// Review 审核题解，status 只能是通过或驳回，驳回时可以附带原因
+func (s *WriteupService) Review(ctx context.Context, id, reviewerId, status, reason string) error {
+	if status != models.WriteupStatusApproved && status != models.WriteupStatusRejected {
+		return xe.ErrInvalidParams
+	}
+	exists, err := s.WriteupRepo.ExistsById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return xe.ErrWriteupNotFound
+	}
+	if status == models.WriteupStatusApproved {
+		reason = ""
+	}
+	return s.WriteupRepo.UpdateColumnsById(ctx, id, orz.Map{
+		"status":      status,
+		"reason":      reason,
+		"reviewer_id": reviewerId,
+		"reviewed_at": time.Now().UnixMilli(),
+	})
+}
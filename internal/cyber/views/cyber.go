@@ -13,19 +13,71 @@ type ChallengeDetail struct {
 	AttemptCount int64 `json:"attempt_count"` // 挑战次数
 	SolvedCount  int64 `json:"solved_count"`  // 成功人数
 	Solved       bool  `json:"solved"`        // 是否已解决
+	Locked       bool  `json:"locked"`        // 是否未解锁，未解锁时不返回描述
+
+	DescriptionHtml string `json:"description_html"` // 描述渲染并清理后的 HTML
+
+	Tags []string `json:"tags"` // 标签
+
+	Feedback FeedbackSummary `json:"feedback"` // 玩家评价汇总
+
+	CurrentPoints int64 `json:"current_points"` // 当前分值，动态计分时随解题人数衰减
+
+	Attachments []AttachmentView `json:"attachments"` // 附件
+	Hints       []HintView       `json:"hints"`       // 提示
+	Stages      []FlagStageView  `json:"stages"`      // Flag 阶段，未配置时为空
+}
+
+// FlagStageView 玩家看到的 Flag 阶段
+type FlagStageView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Points   int64  `json:"points"`   // 提交该阶段获得的分数
+	Optional bool   `json:"optional"` // 可选阶段
+	Captured bool   `json:"captured"` // 是否已提交
+}
+
+// FlagResult 提交 Flag 的结果
+type FlagResult struct {
+	Ok       bool     `json:"ok"`                 // Flag 是否正确
+	Stage    string   `json:"stage,omitempty"`    // 命中的阶段
+	Solved   bool     `json:"solved"`             // 题目是否已完成
+	Captured []string `json:"captured,omitempty"` // 已提交的阶段
+}
+
+// HintView 玩家看到的提示，未解锁时不包含内容
+type HintView struct {
+	ID       string `json:"id"`
+	Cost     int64  `json:"cost"`              // 解锁扣除的分数
+	Unlocked bool   `json:"unlocked"`          // 是否已解锁
+	Content  string `json:"content,omitempty"` // 提示内容
+}
+
+// AttachmentView 玩家可见的附件信息，下载地址需单独获取
+type AttachmentView struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
 }
 
 type ChallengeSimple struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`          // 题目名称 (e.g., "Easy SQL Injection")
-	Category     string `json:"category"`      // 题目类别 (Web、Pwn、Crypto等)
-	Difficulty   string `json:"difficulty"`    // 难度等级 (Easy、Medium、Hard)
-	Points       int64  `json:"points"`        // 题目分值
-	CreatedAt    int64  `json:"created_at"`    // 创建时间
-	UpdatedAt    int64  `json:"updated_at"`    // 更新时间
-	AttemptCount int64  `json:"attempt_count"` // 挑战次数
-	SolvedCount  int64  `json:"solved_count"`  // 成功人数
-	Solved       bool   `json:"solved"`        // 是否已解决
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`            // 题目名称 (e.g., "Easy SQL Injection")
+	Category     string   `json:"category"`        // 题目类别 (Web、Pwn、Crypto等)
+	Difficulty   string   `json:"difficulty"`      // 难度等级 (easy、medium、hard)
+	Points       int64    `json:"points"`          // 题目当前分值
+	CreatedAt    int64    `json:"created_at"`      // 创建时间
+	UpdatedAt    int64    `json:"updated_at"`      // 更新时间
+	AttemptCount int64    `json:"attempt_count"`   // 挑战次数
+	SolvedCount  int64    `json:"solved_count"`    // 成功人数
+	Solved       bool     `json:"solved"`          // 是否已解决
+	Instance     bool     `json:"instance"`        // 是否需要启动环境
+	Locked       bool     `json:"locked"`          // 是否未解锁
+	Tags         []string `json:"tags"`            // 标签
+	Score        int      `json:"score,omitempty"` // 搜索的匹配得分
+
+	Rating      float64 `json:"rating"`       // 平均评分
+	RatingCount int64   `json:"rating_count"` // 评价人数
 }
 
 type InstanceView struct {
@@ -42,6 +94,8 @@ type SolveView struct {
 	ChallengeId   string `json:"challenge_id"`
 	ChallengeName string `json:"challenge_name"`
 	Points        int64  `json:"points"`        // 题目分值
+	Bonus         int64  `json:"bonus"`         // 前 N 名解题奖励
+	SolveOrder    int64  `json:"solve_order"`   // 第几个解出该题
 	StartAt       int64  `json:"start_at"`      // 开始时间
 	SolvedAt      int64  `json:"solved_at"`     // 通过时间
 	UsedTime      int64  `json:"used_time"`     // 使用时长
@@ -56,3 +110,112 @@ type RankView struct {
 	TotalTime    int64  `json:"totalTime"`    // 总耗时（秒）
 	TotalTimeStr string `json:"totalTimeStr"` // 总耗时字符串
 }
+
+// UserView 用户的公开信息
+type UserView struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+// ChallengeGraph 题目依赖图
+type ChallengeGraph struct {
+	Nodes  []ChallengeNode `json:"nodes"`
+	Edges  []ChallengeEdge `json:"edges"`
+	Cycles [][]string      `json:"cycles"` // 循环依赖，每个环为题目ID列表
+}
+
+type ChallengeNode struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	Category            string `json:"category"`
+	Enabled             bool   `json:"enabled"`
+	UnlockScore         int64  `json:"unlock_score"`
+	UnlockCategory      string `json:"unlock_category"`
+	UnlockCategoryCount int64  `json:"unlock_category_count"`
+}
+
+// ChallengeEdge From 是 To 的前置题目
+type ChallengeEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// WriteupView 题解，包含作者和题目信息
+type WriteupView struct {
+	ID            string `json:"id"`
+	ChallengeId   string `json:"challenge_id"`
+	ChallengeName string `json:"challenge_name"`
+	UserId        string `json:"user_id"`
+	UserName      string `json:"user_name"`
+	UserAvatar    string `json:"user_avatar"`
+	Content       string `json:"content"`
+	ContentHtml   string `json:"content_html" gorm:"-"` // 内容渲染并清理后的 HTML，展示时只使用该字段
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	ReviewedAt    int64  `json:"reviewed_at"`
+	CreatedAt     int64  `json:"created_at"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// FeedbackSummary 题目的评价汇总
+type FeedbackSummary struct {
+	Rating          float64          `json:"rating"`           // 平均评分，保留两位小数
+	RatingCount     int64            `json:"rating_count"`     // 评价人数
+	DifficultyVotes map[string]int64 `json:"difficulty_votes"` // 体感难度投票
+}
+
+// FeedbackView 管理端查看的评价，包含用户和题目信息
+type FeedbackView struct {
+	ID            string `json:"id"`
+	ChallengeId   string `json:"challenge_id"`
+	ChallengeName string `json:"challenge_name"`
+	UserId        string `json:"user_id"`
+	UserName      string `json:"user_name"`
+	Rating        int    `json:"rating"`
+	Difficulty    string `json:"difficulty"`
+	Comment       string `json:"comment"`
+	CreatedAt     int64  `json:"created_at"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// ChallengeHealth 题目最近一次健康检查的结果
+type ChallengeHealth struct {
+	ChallengeId   string `json:"challenge_id"`
+	ChallengeName string `json:"challenge_name"`
+	Passed        bool   `json:"passed"`
+	ExitCode      int    `json:"exit_code"`
+	Error         string `json:"error"`
+	CreatedAt     int64  `json:"created_at"` // 检查时间
+}
+
+// SubmissionView 管理端查看的 Flag 提交记录，包含用户和题目信息
+type SubmissionView struct {
+	ID            string `json:"id"`
+	UserId        string `json:"user_id"`
+	UserName      string `json:"user_name"`
+	ChallengeId   string `json:"challenge_id"`
+	ChallengeName string `json:"challenge_name"`
+	InstanceId    string `json:"instance_id"`
+	Flag          string `json:"flag"`
+	Correct       bool   `json:"correct"`
+	Stage         string `json:"stage"`
+	Limited       bool   `json:"limited"`
+	IP            string `json:"ip"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// SubmissionStats 题目的提交统计
+type SubmissionStats struct {
+	Total        int64         `json:"total"`         // 提交次数
+	Correct      int64         `json:"correct"`       // 正确的提交次数
+	Users        int64         `json:"users"`         // 提交过的人数
+	WrongAnswers []WrongAnswer `json:"wrong_answers"` // 最常见的错误答案
+}
+
+// WrongAnswer 一个错误答案的提交情况
+type WrongAnswer struct {
+	Flag  string `json:"flag"`
+	Count int64  `json:"count"` // 提交次数
+	Users int64  `json:"users"` // 提交过的人数
+}
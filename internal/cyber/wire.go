@@ -12,7 +12,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func ProviderDependency(logger *zap.Logger, db *gorm.DB, conf *config.Config) *Dependency {
+func ProviderDependency(logger *zap.Logger, db *gorm.DB, conf *config.Config) (*Dependency, error) {
 	panic(wire.Build(appSet))
 }
 
@@ -29,14 +29,41 @@ var apiSet = wire.NewSet(
 	handler.NewInstanceHandler,
 	handler.NewDashboardHandler,
 	handler.NewSolveHandler,
+	handler.NewGatewayHandler,
+	handler.NewAttachmentHandler,
+	handler.NewHintHandler,
+	handler.NewFlagHandler,
+	handler.NewAnnouncementHandler,
+	handler.NewCategoryHandler,
+	handler.NewTagHandler,
+	handler.NewWriteupHandler,
+	handler.NewChallengeFeedbackHandler,
+	handler.NewChallengeSyncHandler,
+	handler.NewHealthCheckHandler,
+	handler.NewSubmissionHandler,
 )
 
 var serviceSet = wire.NewSet(
 	service.NewChallengeRecordService,
 	service.NewChallengeService,
+	service.NewChallengeBundleService,
+	service.NewAttachmentService,
+	service.NewHintService,
+	service.NewFlagService,
+	service.NewAnnouncementService,
+	service.NewCategoryService,
+	service.NewTagService,
+	service.NewChallengeRevisionService,
+	service.NewWriteupService,
+	service.NewChallengeFeedbackService,
+	service.NewSubmissionLimitService,
+	service.NewSubmissionService,
+	service.NewHealthCheckService,
 	service.NewImageService,
 	service.NewInstanceService,
 	service.NewSolveService,
 	service.NewRankService,
 	service.NewReverseProxyService,
+	service.NewGatewayTLSService,
+	service.NewDNSService,
 )
@@ -17,36 +17,91 @@ import (
 
 // Injectors from wire.go:
 
-func ProviderDependency(logger *zap.Logger, db *gorm.DB, conf *config.Config) *Dependency {
+func ProviderDependency(logger *zap.Logger, db *gorm.DB, conf *config.Config) (*Dependency, error) {
 	challengeService := service.NewChallengeService(db)
 	challengeRecordService := service.NewChallengeRecordService(db)
-	challengeHandler := handler.NewChallengeHandler(challengeService, challengeRecordService)
-	imageService := service.NewImageService(db)
+	categoryService := service.NewCategoryService(db)
+	tagService := service.NewTagService(db)
+	challengeRevisionService := service.NewChallengeRevisionService(db)
+	attachmentService, err := service.NewAttachmentService(db, conf)
+	if err != nil {
+		return nil, err
+	}
+	hintService := service.NewHintService(db)
+	flagService := service.NewFlagService(db)
+	challengeBundleService := service.NewChallengeBundleService(db, challengeService, categoryService, tagService, challengeRevisionService, attachmentService, hintService, flagService)
+	imageService := service.NewImageService(db, conf, logger)
+	writeupService := service.NewWriteupService(db)
+	challengeFeedbackService := service.NewChallengeFeedbackService(db)
+	challengeHandler := handler.NewChallengeHandler(challengeService, challengeRecordService, challengeBundleService, imageService, attachmentService, hintService, flagService, tagService, challengeRevisionService, writeupService, challengeFeedbackService)
 	imageHandler := handler.NewImageHandler(imageService)
 	solveService := service.NewSolveService(db)
-	reverseProxyService := service.NewReverseProxyService(logger)
-	instanceService := service.NewInstanceService(db, logger, conf, challengeService, challengeRecordService, imageService, solveService, reverseProxyService)
-	rankService := service.NewRankService(db)
-	indexHandler := handler.NewIndexHandler(challengeService, instanceService, solveService, challengeRecordService, rankService)
+	reverseProxyService := service.NewReverseProxyService(logger, db, conf)
+	instanceService := service.NewInstanceService(db, logger, conf, challengeService, challengeRecordService, imageService, solveService, reverseProxyService, flagService)
+	rankService := service.NewRankService(db, solveService)
+	submissionLimitService := service.NewSubmissionLimitService(conf)
+	submissionService := service.NewSubmissionService(logger, db)
+	indexHandler := handler.NewIndexHandler(challengeService, instanceService, solveService, challengeRecordService, rankService, attachmentService, hintService, flagService, tagService, challengeFeedbackService, submissionLimitService, submissionService)
 	instanceHandler := handler.NewInstanceHandler(instanceService)
-	dashboardHandler := handler.NewDashboardHandler(challengeService, instanceService, solveService)
+	healthCheckService := service.NewHealthCheckService(db, logger, conf, imageService, flagService)
+	dashboardHandler := handler.NewDashboardHandler(challengeService, instanceService, solveService, healthCheckService)
 	solveHandler := handler.NewSolveHandler(solveService, rankService, challengeService)
+	gatewayTLSService := service.NewGatewayTLSService(conf, reverseProxyService)
+	gatewayHandler := handler.NewGatewayHandler(conf, gatewayTLSService)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService, challengeService)
+	hintHandler := handler.NewHintHandler(hintService, challengeService)
+	flagHandler := handler.NewFlagHandler(flagService, challengeService)
+	announcementService := service.NewAnnouncementService(db, logger)
+	announcementHandler := handler.NewAnnouncementHandler(announcementService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	tagHandler := handler.NewTagHandler(tagService, challengeService)
+	writeupHandler := handler.NewWriteupHandler(writeupService)
+	challengeFeedbackHandler := handler.NewChallengeFeedbackHandler(challengeFeedbackService)
+	challengeSyncHandler := handler.NewChallengeSyncHandler(conf, challengeBundleService, imageService)
+	healthCheckHandler := handler.NewHealthCheckHandler(healthCheckService)
+	submissionHandler := handler.NewSubmissionHandler(submissionService)
+	dnsService := service.NewDNSService(logger, conf, reverseProxyService)
 	dependency := &Dependency{
-		ChallengeHandler:       challengeHandler,
-		ImageHandler:           imageHandler,
-		IndexHandler:           indexHandler,
-		InstanceHandler:        instanceHandler,
-		DashboardHandler:       dashboardHandler,
-		SolveHandler:           solveHandler,
-		ChallengeService:       challengeService,
-		ChallengeRecordService: challengeRecordService,
-		ImageService:           imageService,
-		InstanceService:        instanceService,
-		SolveService:           solveService,
-		RankService:            rankService,
-		ReverseProxyService:    reverseProxyService,
+		ChallengeHandler:         challengeHandler,
+		ImageHandler:             imageHandler,
+		IndexHandler:             indexHandler,
+		InstanceHandler:          instanceHandler,
+		DashboardHandler:         dashboardHandler,
+		SolveHandler:             solveHandler,
+		GatewayHandler:           gatewayHandler,
+		AttachmentHandler:        attachmentHandler,
+		HintHandler:              hintHandler,
+		FlagHandler:              flagHandler,
+		AnnouncementHandler:      announcementHandler,
+		CategoryHandler:          categoryHandler,
+		TagHandler:               tagHandler,
+		WriteupHandler:           writeupHandler,
+		ChallengeFeedbackHandler: challengeFeedbackHandler,
+		ChallengeSyncHandler:     challengeSyncHandler,
+		HealthCheckHandler:       healthCheckHandler,
+		SubmissionHandler:        submissionHandler,
+		ChallengeService:         challengeService,
+		ChallengeRecordService:   challengeRecordService,
+		ChallengeBundleService:   challengeBundleService,
+		AttachmentService:        attachmentService,
+		HintService:              hintService,
+		FlagService:              flagService,
+		AnnouncementService:      announcementService,
+		CategoryService:          categoryService,
+		TagService:               tagService,
+		ChallengeRevisionService: challengeRevisionService,
+		WriteupService:           writeupService,
+		ChallengeFeedbackService: challengeFeedbackService,
+		HealthCheckService:       healthCheckService,
+		ImageService:             imageService,
+		InstanceService:          instanceService,
+		SolveService:             solveService,
+		RankService:              rankService,
+		ReverseProxyService:      reverseProxyService,
+		GatewayTLSService:        gatewayTLSService,
+		DNSService:               dnsService,
 	}
-	return dependency
+	return dependency, nil
 }
 
 // wire.go:
@@ -56,6 +111,6 @@ var appSet = wire.NewSet(
 	apiSet, wire.Struct(new(Dependency), "*"),
 )
 
-var apiSet = wire.NewSet(handler.NewChallengeHandler, handler.NewImageHandler, handler.NewIndexHandler, handler.NewInstanceHandler, handler.NewDashboardHandler, handler.NewSolveHandler)
+var apiSet = wire.NewSet(handler.NewChallengeHandler, handler.NewImageHandler, handler.NewIndexHandler, handler.NewInstanceHandler, handler.NewDashboardHandler, handler.NewSolveHandler, handler.NewGatewayHandler, handler.NewAttachmentHandler, handler.NewHintHandler, handler.NewFlagHandler, handler.NewAnnouncementHandler, handler.NewCategoryHandler, handler.NewTagHandler, handler.NewWriteupHandler, handler.NewChallengeFeedbackHandler, handler.NewChallengeSyncHandler, handler.NewHealthCheckHandler, handler.NewSubmissionHandler)
 
-var serviceSet = wire.NewSet(service.NewChallengeRecordService, service.NewChallengeService, service.NewImageService, service.NewInstanceService, service.NewSolveService, service.NewRankService, service.NewReverseProxyService)
+var serviceSet = wire.NewSet(service.NewChallengeRecordService, service.NewChallengeService, service.NewChallengeBundleService, service.NewAttachmentService, service.NewHintService, service.NewFlagService, service.NewAnnouncementService, service.NewCategoryService, service.NewTagService, service.NewChallengeRevisionService, service.NewWriteupService, service.NewChallengeFeedbackService, service.NewSubmissionLimitService, service.NewSubmissionService, service.NewHealthCheckService, service.NewImageService, service.NewInstanceService, service.NewSolveService, service.NewRankService, service.NewReverseProxyService, service.NewGatewayTLSService, service.NewDNSService)
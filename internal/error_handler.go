@@ -3,6 +3,7 @@ package internal
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/dushixiang/cyberpoc/pkg/xe"
 	"github.com/go-orz/orz"
@@ -22,6 +23,16 @@ func WithErrorHandler(logger *zap.Logger) func(next echo.HandlerFunc) echo.Handl
 					})
 				}
 
+				var re *xe.RateLimitError
+				if errors.As(err, &re) {
+					c.Response().Header().Set("Retry-After", strconv.FormatInt(re.RetryAfter, 10))
+					return c.JSON(http.StatusTooManyRequests, orz.Map{
+						"code":        xe.ErrFlagRateLimited.Code,
+						"message":     err.Error(),
+						"retry_after": re.RetryAfter,
+					})
+				}
+
 				var oe *orz.Error
 				if errors.As(err, &oe) {
 					var code = 400
@@ -0,0 +1,199 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 90 * 24 * time.Hour
+	// 证书剩余有效期不足时重新签发
+	leafRenewBefore = 7 * 24 * time.Hour
+	// 缓存的证书数量上限，超出时淘汰最早签发的证书
+	maxLeaves = 1024
+)
+
+// LocalCA 本地证书颁发机构，按需为域名签发证书
+type LocalCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+
+	mu     sync.Mutex
+	leaves map[string]*tls.Certificate
+}
+
+// LoadOrCreateLocalCA 从目录加载 CA，不存在时自动生成
+func LoadOrCreateLocalCA(dir string) (*LocalCA, error) {
+	certPath := filepath.Join(dir, caCertFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := createCA(dir, certPath, keyPath); err != nil {
+			return nil, err
+		}
+		certPEM, err = os.ReadFile(certPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("invalid ca certificate: %s", certPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("invalid ca key: %s", keyPath)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalCA{
+		cert:    cert,
+		key:     key,
+		certPEM: certPEM,
+		leaves:  make(map[string]*tls.Certificate),
+	}, nil
+}
+
+func createCA(dir, certPath, keyPath string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   "CyberPoC Local CA",
+			Organization: []string{"CyberPoC"},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// CertificatePEM CA 根证书，供用户导入信任
+func (ca *LocalCA) CertificatePEM() []byte {
+	return ca.certPEM
+}
+
+// Certificate 获取域名对应的证书，缓存未命中或即将过期时重新签发
+func (ca *LocalCA) Certificate(host string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if cert, ok := ca.leaves[host]; ok && time.Until(cert.Leaf.NotAfter) > leafRenewBefore {
+		return cert, nil
+	}
+	cert, err := ca.issue(host)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ca.leaves[host]; !ok && len(ca.leaves) >= maxLeaves {
+		ca.evictOldest()
+	}
+	ca.leaves[host] = cert
+	return cert, nil
+}
+
+// evictOldest 淘汰最早签发的证书
+func (ca *LocalCA) evictOldest() {
+	var oldest string
+	var notBefore time.Time
+	for host, cert := range ca.leaves {
+		if oldest == "" || cert.Leaf.NotBefore.Before(notBefore) {
+			oldest, notBefore = host, cert.Leaf.NotBefore
+		}
+	}
+	delete(ca.leaves, oldest)
+}
+
+func (ca *LocalCA) issue(host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
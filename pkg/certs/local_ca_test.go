@@ -0,0 +1,55 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"testing"
+)
+
+func TestLocalCA(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := ca.Certificate("abc.vuln.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.CertificatePEM())
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "abc.vuln.example.com",
+		Roots:   pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 再次加载应复用同一个 CA
+	reloaded, err := LoadOrCreateLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reloaded.CertificatePEM(), ca.CertificatePEM()) {
+		t.Fatal("ca changed after reload")
+	}
+}
+
+func TestLocalCALeafLimit(t *testing.T) {
+	ca, err := LoadOrCreateLocalCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxLeaves+10; i++ {
+		if _, err := ca.Certificate(fmt.Sprintf("i%d.vuln.example.com", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ca.leaves) != maxLeaves {
+		t.Fatalf("expected %d cached leaves, got %d", maxLeaves, len(ca.leaves))
+	}
+}
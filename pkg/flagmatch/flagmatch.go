@@ -0,0 +1,90 @@
+package flagmatch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 匹配方式
+const (
+	Exact           = "exact"            // 完全一致
+	CaseInsensitive = "case-insensitive" // 忽略大小写，为空时的默认方式
+	Regex           = "regex"            // 正则表达式，需要匹配整个 Flag
+	Normalized      = "normalized"       // 去掉首尾空白后完全一致
+)
+
+// wrapperPattern 匹配 flag{...}、ctf{...} 等包裹格式
+var wrapperPattern = regexp.MustCompile(`^[A-Za-z0-9_-]*\{(.*)\}$`)
+
+var regexCache sync.Map
+
+// Validate 校验匹配方式和期望的 Flag
+func Validate(mode, expected string) error {
+	switch mode {
+	case "", Exact, CaseInsensitive, Normalized:
+		return nil
+	case Regex:
+		if expected == "" {
+			return fmt.Errorf("正则表达式不能为空")
+		}
+		_, err := compile(expected)
+		return err
+	default:
+		return fmt.Errorf("不支持的匹配方式: %s", mode)
+	}
+}
+
+// Match 按匹配方式比较提交的 Flag，stripWrapper 为 true 时双方都去掉 flag{} 等包裹后再比较；
+// 正则匹配时只去掉提交内容的包裹，正则表达式需要匹配包裹内的部分
+func Match(mode, expected, submitted string, stripWrapper bool) bool {
+	if mode == Regex {
+		re, err := compile(expected)
+		if err != nil {
+			return false
+		}
+		submitted = strings.TrimSpace(submitted)
+		if stripWrapper {
+			submitted = unwrap(submitted)
+		}
+		return re.MatchString(submitted)
+	}
+
+	if mode == Normalized {
+		expected = strings.TrimSpace(expected)
+		submitted = strings.TrimSpace(submitted)
+	}
+	if stripWrapper {
+		expected = unwrap(expected)
+		submitted = unwrap(submitted)
+	}
+	if expected == "" {
+		return false
+	}
+	switch mode {
+	case Exact, Normalized:
+		return expected == submitted
+	default:
+		return strings.EqualFold(expected, submitted)
+	}
+}
+
+func unwrap(flag string) string {
+	if m := wrapperPattern.FindStringSubmatch(flag); m != nil {
+		return m[1]
+	}
+	return flag
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("正则表达式无效: %w", err)
+	}
+	regexCache.Store(expr, re)
+	return re, nil
+}
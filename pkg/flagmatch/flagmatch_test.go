@@ -0,0 +1,39 @@
+package flagmatch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		mode, expected, submitted string
+		strip                     bool
+		want                      bool
+	}{
+		{"", "flag{Abc}", "FLAG{abc}", false, true},
+		{Exact, "flag{Abc}", "flag{abc}", false, false},
+		{Exact, "flag{Abc}", "flag{Abc}", false, true},
+		{Normalized, "flag{Abc}", "  flag{Abc}\n", false, true},
+		{Normalized, "flag{Abc}", " Abc ", true, true},
+		{Normalized, "flag{Abc}", "abc", true, false},
+		{Regex, `flag\{sql_?injection\}`, "flag{sqlinjection}", false, true},
+		{Regex, `flag\{sql\}`, "xflag{sql}", false, false},
+		{Regex, `sql_?injection`, "CTF{sql_injection}", true, true},
+		{CaseInsensitive, "", "", false, false},
+	}
+	for _, c := range cases {
+		if got := Match(c.mode, c.expected, c.submitted, c.strip); got != c.want {
+			t.Errorf("Match(%q, %q, %q, %v) = %v, want %v", c.mode, c.expected, c.submitted, c.strip, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(Regex, `flag\{(`); err == nil {
+		t.Error("expected invalid regex error")
+	}
+	if err := Validate("fuzzy", "flag"); err == nil {
+		t.Error("expected unsupported mode error")
+	}
+	if err := Validate(Normalized, "flag"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,130 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Rule 限流规则：Window 内最多 Limit 次，超出后冷却 Cooldown，连续超出时冷却时间翻倍，不超过 MaxCooldown
+type Rule struct {
+	Limit       int
+	Window      time.Duration
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+}
+
+// Key 一个限流对象及其规则
+type Key struct {
+	Name string
+	Rule Rule
+}
+
+type entry struct {
+	start   time.Time     // 当前窗口的开始时间
+	window  time.Duration // 窗口长度
+	count   int           // 当前窗口内的次数
+	strikes int           // 连续超出限制的次数
+	until   time.Time     // 冷却结束时间
+	reset   time.Time     // 超过该时间未再超出限制时清空 strikes
+}
+
+// Limiter 固定窗口计数 + 递增冷却，只在单个进程内生效
+type Limiter struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+	now     func() time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Take 为所有 key 各计一次，任意 key 处于冷却中或超出限制时都不计数，返回需要等待的时间，为 0 时表示放行
+func (l *Limiter) Take(keys ...Key) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, key := range keys {
+		if key.Rule.Limit <= 0 {
+			continue
+		}
+		e := l.entry(key, now)
+		if now.Before(e.until) {
+			wait = max(wait, e.until.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	for _, key := range keys {
+		if key.Rule.Limit <= 0 {
+			continue
+		}
+		e := l.entries[key.Name]
+		if e.count < key.Rule.Limit {
+			continue
+		}
+		cooldown := key.Rule.Cooldown << e.strikes
+		if cooldown > key.Rule.MaxCooldown || cooldown <= 0 {
+			cooldown = key.Rule.MaxCooldown
+		}
+		e.strikes++
+		e.until = now.Add(cooldown)
+		e.reset = e.until.Add(key.Rule.MaxCooldown)
+		// 冷却结束后重新开始计数
+		e.start = e.until
+		e.count = 0
+		wait = max(wait, cooldown)
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	for _, key := range keys {
+		if key.Rule.Limit <= 0 {
+			continue
+		}
+		l.entries[key.Name].count++
+	}
+	return 0
+}
+
+// entry 取出 key 对应的计数，窗口过期时重新开始
+func (l *Limiter) entry(key Key, now time.Time) *entry {
+	e, ok := l.entries[key.Name]
+	if !ok {
+		e = &entry{start: now}
+		l.entries[key.Name] = e
+	}
+	e.window = key.Rule.Window
+	if !now.Before(e.start.Add(e.window)) {
+		e.start = now
+		e.count = 0
+	}
+	if e.strikes > 0 && now.After(e.reset) {
+		e.strikes = 0
+	}
+	return e
+}
+
+// sweep 每分钟清理一次已经不再影响限流的计数
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for name, e := range l.entries {
+		if !now.Before(e.start.Add(e.window)) && now.After(e.reset) {
+			delete(l.entries, name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+
+	rule := Rule{Limit: 2, Window: time.Minute, Cooldown: 10 * time.Second, MaxCooldown: 25 * time.Second}
+	key := Key{Name: "user", Rule: rule}
+
+	for i := 0; i < 2; i++ {
+		if wait := l.Take(key); wait != 0 {
+			t.Fatalf("take %d: wait = %v", i, wait)
+		}
+	}
+	if wait := l.Take(key); wait != 10*time.Second {
+		t.Fatalf("first strike: wait = %v", wait)
+	}
+	now = now.Add(4 * time.Second)
+	if wait := l.Take(key); wait != 6*time.Second {
+		t.Fatalf("cooling down: wait = %v", wait)
+	}
+
+	// 冷却结束后重新计数，再次超出时冷却时间翻倍
+	now = now.Add(6 * time.Second)
+	l.Take(key)
+	l.Take(key)
+	if wait := l.Take(key); wait != 20*time.Second {
+		t.Fatalf("second strike: wait = %v", wait)
+	}
+	now = now.Add(20 * time.Second)
+	l.Take(key)
+	l.Take(key)
+	if wait := l.Take(key); wait != 25*time.Second {
+		t.Fatalf("capped strike: wait = %v", wait)
+	}
+
+	// 长时间没有超出限制后恢复初始冷却时间
+	now = now.Add(25*time.Second + 26*time.Second)
+	l.Take(key)
+	l.Take(key)
+	if wait := l.Take(key); wait != 10*time.Second {
+		t.Fatalf("after reset: wait = %v", wait)
+	}
+}
+
+func TestTakeMultipleKeys(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+
+	tight := Key{Name: "challenge", Rule: Rule{Limit: 1, Window: time.Minute, Cooldown: time.Minute, MaxCooldown: time.Hour}}
+	loose := Key{Name: "ip", Rule: Rule{Limit: 10, Window: time.Minute, Cooldown: time.Minute, MaxCooldown: time.Hour}}
+	unlimited := Key{Name: "none"}
+
+	if wait := l.Take(tight, loose, unlimited); wait != 0 {
+		t.Fatalf("wait = %v", wait)
+	}
+	if wait := l.Take(tight, loose, unlimited); wait != time.Minute {
+		t.Fatalf("wait = %v", wait)
+	}
+	// 被拒绝的请求不计入其他 key
+	if got := l.entries["ip"].count; got != 1 {
+		t.Fatalf("ip count = %d", got)
+	}
+	if _, ok := l.entries["none"]; ok {
+		t.Fatal("unlimited key should not be tracked")
+	}
+}
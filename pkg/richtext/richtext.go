@@ -0,0 +1,47 @@
+package richtext
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	policy = newPolicy()
+
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+)
+
+// newPolicy 白名单：常用排版标签、表格、图片和 http(s)/mailto 链接，不允许脚本、样式、事件属性和 iframe
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	// 代码块的语言标记，用于前端高亮
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return p
+}
+
+// Sanitize 按白名单清理 HTML
+func Sanitize(html string) string {
+	if html == "" {
+		return ""
+	}
+	return policy.Sanitize(html)
+}
+
+// Markdown 把 markdown 渲染为清理后的 HTML，渲染失败时返回转义后的原文
+func Markdown(source string) string {
+	if source == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return policy.Sanitize("<pre>" + bluemonday.StrictPolicy().Sanitize(source) + "</pre>")
+	}
+	return policy.Sanitize(buf.String())
+}
@@ -0,0 +1,44 @@
+package richtext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	var tests = []struct {
+		input    string
+		contains string
+		excludes string
+	}{
+		{`<p>hello</p><script>alert(1)</script>`, `<p>hello</p>`, `script`},
+		{`<img src="x" onerror="alert(1)">`, `<img src="x">`, `onerror`},
+		{`<a href="javascript:alert(1)">x</a>`, `x`, `javascript`},
+		{`<a href="https://example.com">x</a>`, `rel="nofollow noopener"`, ``},
+		{`<iframe src="https://example.com"></iframe>`, ``, `iframe`},
+		{`<div style="position:fixed">x</div>`, `x`, `style`},
+	}
+	for _, tt := range tests {
+		got := Sanitize(tt.input)
+		if tt.contains != "" && !strings.Contains(got, tt.contains) {
+			t.Errorf("Sanitize(%q) = %q, want contains %q", tt.input, got, tt.contains)
+		}
+		if tt.excludes != "" && strings.Contains(got, tt.excludes) {
+			t.Errorf("Sanitize(%q) = %q, want excludes %q", tt.input, got, tt.excludes)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got := Markdown("# Title\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```php\n<?php echo 1;\n```\n\n<script>alert(1)</script>\n\n[x](javascript:alert(1))")
+	for _, want := range []string{`<h1`, `<table>`, `<code class="language-php">`, `&lt;?php`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown() = %q, want contains %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{`<script`, `javascript:`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Markdown() = %q, want excludes %q", got, unwanted)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage 附件等文件的存储后端
+type Storage interface {
+	// Put 写入文件，返回写入的字节数
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open 读取文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTar(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	var files = make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestTarDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "index.php"), []byte("<?php"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := TarDir(dir, &buf); err != nil {
+		t.Fatal(err)
+	}
+	files := readTar(t, buf.Bytes())
+	if files["Dockerfile"] != "FROM scratch" || files["src/index.php"] != "<?php" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	if _, ok := files["src/"]; !ok {
+		t.Fatalf("missing directory entry: %v", files)
+	}
+}
+
+func TestZipToTar(t *testing.T) {
+	newZip := func(names ...string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write([]byte(name))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	data := newZip("Dockerfile", "src/index.php")
+	var buf bytes.Buffer
+	if err := ZipToTar(bytes.NewReader(data), int64(len(data)), &buf); err != nil {
+		t.Fatal(err)
+	}
+	files := readTar(t, buf.Bytes())
+	if files["Dockerfile"] != "Dockerfile" || files["src/index.php"] != "src/index.php" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	data = newZip("../etc/passwd")
+	if err := ZipToTar(bytes.NewReader(data), int64(len(data)), io.Discard); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	fh := &zip.FileHeader{Name: "current"}
+	fh.SetMode(os.ModeSymlink | 0777)
+	lw, err := zw.CreateHeader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = lw.Write([]byte("releases/v1"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data = zbuf.Bytes()
+	buf.Reset()
+	if err := ZipToTar(bytes.NewReader(data), int64(len(data)), &buf); err != nil {
+		t.Fatal(err)
+	}
+	header, err := tar.NewReader(&buf).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Typeflag != tar.TypeSymlink || header.Linkname != "releases/v1" {
+		t.Fatalf("unexpected symlink header %+v", header)
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldChange 字段变更
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// DiffStruct 按 json 标签逐字段比较两个同类型结构体，ignore 中的字段不参与比较
+func DiffStruct(old, new any, ignore ...string) []FieldChange {
+	ov := reflect.Indirect(reflect.ValueOf(old))
+	nv := reflect.Indirect(reflect.ValueOf(new))
+	if ov.Type() != nv.Type() || ov.Kind() != reflect.Struct {
+		return nil
+	}
+	var skip = make(map[string]bool, len(ignore))
+	for _, field := range ignore {
+		skip[field] = true
+	}
+
+	var changes []FieldChange
+	diffValue(ov, nv, skip, &changes)
+	return changes
+}
+
+func diffValue(ov, nv reflect.Value, skip map[string]bool, changes *[]FieldChange) {
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		// 展开匿名嵌入的结构体
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			diffValue(ov.Field(i), nv.Field(i), skip, changes)
+			continue
+		}
+		name := jsonName(field)
+		if name == "" || skip[name] {
+			continue
+		}
+		o, n := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		*changes = append(*changes, FieldChange{
+			Field: name,
+			Old:   fmt.Sprint(o),
+			New:   fmt.Sprint(n),
+		})
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
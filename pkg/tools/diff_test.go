@@ -0,0 +1,22 @@
+package tools
+
+import "testing"
+
+func TestDiffStruct(t *testing.T) {
+	type item struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Points int64  `json:"points"`
+		Secret string `json:"-"`
+	}
+	old := item{ID: "1", Name: "a", Points: 100, Secret: "x"}
+	new := item{ID: "2", Name: "b", Points: 100, Secret: "y"}
+
+	changes := DiffStruct(old, new, "id")
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+	if changes[0] != (FieldChange{Field: "name", Old: "a", New: "b"}) {
+		t.Fatalf("unexpected change: %v", changes[0])
+	}
+}
@@ -0,0 +1,51 @@
+package tools
+
+import "sort"
+
+// FindCycles 查找有向图中的环，edges 的 key 指向 value 中的每个节点，
+// 每个环从环上最先被访问到的节点开始，按访问顺序返回
+func FindCycles(edges map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var (
+		state  = make(map[string]int)
+		path   []string
+		cycles [][]string
+	)
+
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		path = append(path, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == next {
+						cycles = append(cycles, append([]string(nil), path[i:]...))
+						break
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = visited
+	}
+
+	var nodes = make([]string, 0, len(edges))
+	for node := range edges {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCycles(t *testing.T) {
+	cycles := FindCycles(map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {"a", "d"},
+		"e": {"b"},
+	})
+	want := [][]string{{"a", "b", "c"}, {"d"}}
+	if !reflect.DeepEqual(cycles, want) {
+		t.Errorf("FindCycles() = %v, want %v", cycles, want)
+	}
+
+	if cycles := FindCycles(map[string][]string{"a": {"b"}, "b": {"c"}}); len(cycles) != 0 {
+		t.Errorf("FindCycles() = %v, want none", cycles)
+	}
+}
@@ -1,6 +1,12 @@
 package xe
 
-import "github.com/go-orz/orz"
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-orz/orz"
+)
 
 var (
 	ErrInvalidParams        = orz.NewError(10400, "参数无效")
@@ -23,4 +29,60 @@ var (
 	ErrChallengeNotFound      = orz.NewError(20003, "题目不存在")
 	ErrImageNotFound          = orz.NewError(20004, "镜像不存在")
 	ErrInstanceNotFound       = orz.NewError(20004, "环境不存在")
+	ErrInvalidBundle          = orz.NewError(20007, "题目包无效")
+	ErrAttachmentNotFound     = orz.NewError(20008, "附件不存在")
+	ErrAttachmentTooLarge     = orz.NewError(20009, "附件大小超过限制")
+	ErrInvalidSignature       = orz.NewError(20010, "下载链接无效或已过期")
+	ErrHintNotFound           = orz.NewError(20011, "提示不存在")
+	ErrInvalidScoring         = orz.NewError(20012, "动态计分需要设置衰减人数，且最低分值不能大于初始分值")
+	ErrInvalidBonus           = orz.NewError(20013, "解题奖励方式只能是 points 或 percent，且奖励不能小于0")
+	ErrInvalidFlagStage       = orz.NewError(20014, "阶段名称不能为空，静态阶段需要设置Flag，环境变量名只能包含字母、数字和下划线")
+	ErrInvalidFlagMatch       = orz.NewError(20015, "Flag 匹配方式无效，正则表达式需要能够编译，动态Flag不能使用正则匹配")
+	ErrNoInstanceRequired     = orz.NewError(20016, "该题目无需启动环境，直接提交Flag即可")
+	ErrDynamicFlagNoImage     = orz.NewError(20017, "动态Flag需要配置镜像")
+	ErrInvalidPrerequisite    = orz.NewError(20018, "前置题目不存在或形成循环依赖，解锁条件不能小于0")
+	ErrChallengeLocked        = orz.NewError(20019, "题目尚未解锁，请先完成前置要求")
+	ErrInvalidDifficulty      = orz.NewError(20020, "难度等级只能是 easy、medium 或 hard")
+	ErrCategoryNotFound       = orz.NewError(20021, "类别不存在")
+	ErrCategoryDuplicate      = orz.NewError(20022, "类别名称已存在")
+	ErrCategoryInUse          = orz.NewError(20023, "类别下还有题目，不能删除")
+	ErrInvalidTag             = orz.NewError(20024, "标签不能为空且不超过64个字符")
+	ErrInvalidCategory        = orz.NewError(20025, "类别名称不能为空")
+	ErrRevisionNotFound       = orz.NewError(20026, "修订记录不存在")
+	ErrWriteupNotSolved       = orz.NewError(20027, "通关后才能提交和查看题解")
+	ErrWriteupNotFound        = orz.NewError(20028, "题解不存在")
+	ErrInvalidWriteup         = orz.NewError(20029, "题解内容不能为空且不超过64KB")
+	ErrFeedbackNotAttempted   = orz.NewError(20030, "参与过题目后才能评价")
+	ErrInvalidFeedback        = orz.NewError(20031, "评分只能是1到5，体感难度只能是 easy、medium 或 hard，评论不超过2000个字符")
+	ErrSyncDisabled           = orz.NewError(20032, "未配置题目同步目录")
+	ErrImageBuilding          = orz.NewError(20033, "镜像正在构建中")
+	ErrImageBuildDisabled     = orz.NewError(20034, "未配置镜像构建目录")
+	ErrImageNotPullable       = orz.NewError(20035, "镜像由构建上下文构建，不能从镜像仓库拉取")
+	ErrInvalidBuildContext    = orz.NewError(20036, "构建上下文只支持 tar、tar.gz 或 zip")
+	ErrCheckerNoImage         = orz.NewError(20037, "不需要启动环境的题目不能配置检查器")
+	ErrNoChecker              = orz.NewError(20038, "题目未配置检查器")
+	ErrHealthCheckRunning     = orz.NewError(20039, "健康检查正在进行中")
+	ErrFlagRateLimited        = orz.NewError(20040, "提交过于频繁，请稍后再试")
+	ErrSlugAlreadyUsed        = orz.NewError(20041, "slug 已被其他题目使用")
+	ErrFlagStageNotFound      = orz.NewError(20042, "Flag 阶段不存在")
+	ErrPruneSubPath           = orz.NewError(20043, "只有同步整个根目录时才能停用题目")
+	ErrRegexFlagWithImage     = orz.NewError(20044, "需要启动环境的题目会把 Flag 注入容器，不能使用正则匹配")
+	ErrInvalidSlug            = orz.NewError(20045, "slug 只能包含小写字母、数字、-、_，且不超过64个字符")
 )
+
+// RateLimitError 提交过于频繁，RetryAfter 为需要等待的秒数
+type RateLimitError struct {
+	RetryAfter int64
+}
+
+func NewRateLimitError(wait time.Duration) *RateLimitError {
+	return &RateLimitError{RetryAfter: int64(math.Ceil(wait.Seconds()))}
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s，%d 秒后可再次提交", ErrFlagRateLimited.Error(), e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrFlagRateLimited
+}